@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"errors"
+	"infy/db"
+	"infy/mailer"
+	"infy/models"
+	"infy/utils"
+	"infy/validation"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+)
+
+// sendUserToken creates a single use token for the user and emails them a link to the given page of the frontend.
+func sendUserToken(c *gin.Context, mail mailer.Mailer, user *models.User, purpose, page, subject, text string, ttl time.Duration) error {
+	token, err := utils.NewToken()
+	if err != nil {
+		return err
+	}
+
+	tokenStore := models.UserTokenStore{Collection: db.UserTokensCollection()}
+	userToken := models.NewUserToken(user.ID, purpose, utils.HashToken(token), user.Email, ttl)
+	if err := tokenStore.Save(userToken, c.Request.Context()); err != nil {
+		return err
+	}
+
+	link := utils.GetEnv("APP_URL", "http://localhost:5173") + page + "?token=" + url.QueryEscape(token)
+	return mail.Send(c.Request.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    "Hi " + user.Username + ",\n\n" + text + "\n\n" + link + "\n\nThe link expires in " + ttl.String() + ".\n",
+	})
+}
+
+// sendVerificationEmail emails the user a link to verify their email address.
+func sendVerificationEmail(c *gin.Context, mail mailer.Mailer, user *models.User) error {
+	return sendUserToken(c, mail, user, models.TokenPurposeVerifyEmail, "/verify-email", "Verify your email address",
+		"Please confirm your email address by opening the link below.", verifyEmailTokenTTL)
+}
+
+// VerifyEmail marks the email of the user a verification token was sent to as verified.
+func VerifyEmail(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	tokenStore := models.UserTokenStore{Collection: db.UserTokensCollection()}
+	token, err := tokenStore.ConsumeToken(utils.HashToken(request.Token), models.TokenPurposeVerifyEmail, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	err = models.SetEmailVerified(token.UserID, token.Email, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// The user changed their email after the token was sent
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Email verified"})
+}
+
+// ResendVerificationEmail sends the authenticated user a new verification email.
+func ResendVerificationEmail(mail mailer.Mailer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
+		if user.EmailVerified {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+			return
+		}
+
+		if err := sendVerificationEmail(c, mail, user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not send verification email"})
+			log.Println(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": "Verification email sent"})
+	}
+}
+
+// ForgotPassword emails a password reset link if an account exists for the email address.
+func ForgotPassword(mail mailer.Mailer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+
+		// Always respond the same way so the endpoint cannot be used to find out which emails have accounts
+		response := gin.H{"success": "If an account exists for this email, a reset link has been sent"}
+
+		user, err := models.FindUserByEmail(request.Email, c.Request.Context())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+
+		err = sendUserToken(c, mail, user, models.TokenPurposeResetPassword, "/reset-password", "Reset your password",
+			"Someone asked to reset the password of your account. If this was you, open the link below to choose a new password.", resetPasswordTokenTTL)
+		if err != nil {
+			// Failing only for existing accounts would give them away as well
+			log.Println(err)
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// ResetPassword sets a new password using a reset token and logs the user out of every session.
+func ResetPassword(policy *validation.PasswordPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			Token           string `json:"token" binding:"required"`
+			Password        string `json:"password" binding:"required"`
+			ConfirmPassword string `json:"confirm_password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+
+		// Look the token up without using it, so a password the policy rejects does not cost the user their link
+		tokenStore := models.UserTokenStore{Collection: db.UserTokensCollection()}
+		token, err := tokenStore.FindToken(utils.HashToken(request.Token), models.TokenPurposeResetPassword, c.Request.Context())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+
+		user, err := models.FindUserByID(token.UserID.Hex(), c.Request.Context())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+
+		fields := validation.FieldErrors{}
+		fields.Add("password", policy.Check(request.Password, user.Username, user.Email))
+		if request.Password != request.ConfirmPassword {
+			fields.Add("confirm_password", "Passwords do not match")
+		}
+
+		if !fields.Empty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password", "fields": fields})
+			return
+		}
+
+		token, err = tokenStore.ConsumeToken(token.TokenHash, models.TokenPurposeResetPassword, c.Request.Context())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			log.Println(err)
+			return
+		}
+
+		if err := models.UpdatePassword(token.UserID, string(hashedPassword), c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reset password"})
+			log.Println(err)
+			return
+		}
+
+		// Whoever knew the old password must not stay logged in
+		sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+		if err := sessionStore.RevokeUserSessions(token.UserID, primitive.NilObjectID, c.Request.Context()); err != nil {
+			log.Println(err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": "Password reset"})
+	}
+}
@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"infy/db"
+	"infy/mailer"
+	"infy/models"
+	"infy/utils"
+	"infy/validation"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultDeletionGracePeriod is how long a deleted account can still be restored by logging in
+const defaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+// avatarDir is where uploaded avatars are stored
+const avatarDir = "uploads/avatars"
+
+// reauthenticationWindow is how long logging in again at the provider confirms sensitive changes to an account
+// without a password
+const reauthenticationWindow = 5 * time.Minute
+
+// confirmCurrentPassword checks the password the user typed to confirm a sensitive change, responding if it is wrong.
+// Accounts created through a login provider have no password, they confirm with a two-factor code if they enabled
+// two-factor authentication or by logging in again at the provider during the current session.
+func confirmCurrentPassword(c *gin.Context, user *models.User, password, code string) bool {
+	if user.Password != "" {
+		return confirmSecret(c, user, models.LoginFailureInvalidPassword, "current_password", "Password is incorrect", func() (bool, error) {
+			return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil, nil
+		})
+	}
+
+	if user.TwoFactor.Enabled && code != "" {
+		return confirmSecret(c, user, models.LoginFailureInvalidTwoFactorCode, "code", "Code is incorrect", func() (bool, error) {
+			return verifyTwoFactorCode(c, user, code)
+		})
+	}
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	session, err := sessionStore.FindSessionByID(c.GetString("session_id"), c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return false
+	}
+
+	if session.ReauthenticatedAt != nil && time.Since(*session.ReauthenticatedAt) < reauthenticationWindow {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Log in again to confirm this change", "reauthentication_required": true})
+	return false
+}
+
+// confirmSecret runs the check of a password or code the user typed, responding with the message on the field if it is
+// wrong. Wrong guesses count towards the login lockout so a stolen session cannot be used to guess the secret.
+func confirmSecret(c *gin.Context, user *models.User, failure, field, message string, check func() (bool, error)) bool {
+	retryAfter, err := loginRetryAfter(c, user.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return false
+	}
+
+	if retryAfter > 0 {
+		tooManyLoginAttempts(c, retryAfter)
+		return false
+	}
+
+	ok, err := check()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return false
+	}
+
+	if ok {
+		return true
+	}
+
+	retryAfter, err = recordLoginFailure(c, user.Email, user, failure)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return false
+	}
+
+	if retryAfter > 0 {
+		tooManyLoginAttempts(c, retryAfter)
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "fields": validation.FieldErrors{field: message}})
+	return false
+}
+
+// revokeOtherSessions logs the user out everywhere except the current session after their credentials changed.
+func revokeOtherSessions(c *gin.Context, user *models.User) {
+	currentID, _ := primitive.ObjectIDFromHex(c.GetString("session_id"))
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	if err := sessionStore.RevokeUserSessions(user.ID, currentID, c.Request.Context()); err != nil {
+		log.Println(err)
+	}
+}
+
+// ChangeEmail changes the authenticated user's email address after confirming their password. The new address has to
+// be verified again and the old address is told about the change.
+func ChangeEmail(mail mailer.Mailer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
+
+		var request struct {
+			Email           string `json:"email"`
+			CurrentPassword string `json:"current_password"`
+			Code            string `json:"code"` // Confirms the change instead of the password for accounts without one
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+
+		request.Email = strings.TrimSpace(request.Email)
+
+		fields := validation.FieldErrors{}
+		fields.Required("email", request.Email)
+		fields.Add("email", validation.Email(request.Email))
+		if strings.EqualFold(request.Email, user.Email) {
+			fields.Add("email", "This is already your email address")
+		}
+
+		if !fields.Empty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "fields": fields})
+			return
+		}
+
+		if !confirmCurrentPassword(c, user, request.CurrentPassword, request.Code) {
+			return
+		}
+
+		_, err := models.FindUserByEmail(request.Email, c.Request.Context())
+		if err == nil {
+			fields.Add("email", "This email is already taken")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "fields": fields})
+			return
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+
+		err = models.UpdateEmail(user.ID, request.Email, c.Request.Context())
+		if field := models.DuplicateUserField(err); field != "" {
+			fields.Add(field, "This "+field+" is already taken")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "fields": fields})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not change email"})
+			log.Println(err)
+			return
+		}
+
+		revokeOtherSessions(c, user)
+
+		// The owner of the old address should hear about the change in case it was not them
+		oldEmail := user.Email
+		err = mail.Send(c.Request.Context(), mailer.Message{
+			To:      oldEmail,
+			Subject: "Your email address was changed",
+			Body:    "Hi " + user.Username + ",\n\nThe email address of your account was changed to " + request.Email + ". If this was not you, please reset your password right away.\n",
+		})
+		if err != nil {
+			log.Println(err)
+		}
+
+		user.Email = request.Email
+		user.EmailVerified = false
+		if err := sendVerificationEmail(c, mail, user); err != nil {
+			log.Println(err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": "Email changed, please verify the new address"})
+	}
+}
+
+// ChangePassword changes the authenticated user's password after confirming the current one and logs out every
+// other session.
+func ChangePassword(policy *validation.PasswordPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
+
+		var request struct {
+			CurrentPassword string `json:"current_password"`
+			Code            string `json:"code"` // Confirms the change instead of the password for accounts without one
+			Password        string `json:"password"`
+			ConfirmPassword string `json:"confirm_password"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+
+		fields := validation.FieldErrors{}
+		fields.Required("password", request.Password)
+		fields.Add("password", policy.Check(request.Password, user.Username, user.Email))
+		if request.Password != request.ConfirmPassword {
+			fields.Add("confirm_password", "Passwords do not match")
+		}
+
+		if !fields.Empty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "fields": fields})
+			return
+		}
+
+		if !confirmCurrentPassword(c, user, request.CurrentPassword, request.Code) {
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			log.Println(err)
+			return
+		}
+
+		if err := models.UpdatePassword(user.ID, string(hashedPassword), c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not change password"})
+			log.Println(err)
+			return
+		}
+
+		revokeOtherSessions(c, user)
+
+		c.JSON(http.StatusOK, gin.H{"success": "Password changed"})
+	}
+}
+
+// DeleteAccount schedules the authenticated user's account for deletion after confirming their password and logs
+// them out everywhere. Logging in again before the grace period ends keeps the account.
+func DeleteAccount(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var request struct {
+		CurrentPassword string `json:"current_password"`
+		Code            string `json:"code"` // Confirms the change instead of the password for accounts without one
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if !confirmCurrentPassword(c, user, request.CurrentPassword, request.Code) {
+		return
+	}
+
+	deleteAfter := time.Now().Add(utils.GetEnvDuration("ACCOUNT_DELETION_GRACE", defaultDeletionGracePeriod))
+	if err := models.ScheduleUserDeletion(user.ID, deleteAfter, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete account"})
+		log.Println(err)
+		return
+	}
+
+	// Nothing may keep acting as the user during the grace period
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	if err := sessionStore.RevokeUserSessions(user.ID, primitive.NilObjectID, c.Request.Context()); err != nil {
+		log.Println(err)
+	}
+
+	tokenStore := models.APITokenStore{Collection: db.APITokensCollection()}
+	if err := tokenStore.RevokeUserTokens(user.ID, c.Request.Context()); err != nil {
+		log.Println(err)
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"success": "Account scheduled for deletion, log in again before then to keep it", "delete_after": deleteAfter})
+}
+
+// PurgeDeletedAccounts permanently deletes up to limit accounts whose grace period has ended, along with their avatars.
+// It returns the number of deleted accounts.
+func PurgeDeletedAccounts(ctx context.Context, limit int64) (int, error) {
+	users, err := models.FindUsersDueForDeletion(time.Now(), limit, ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, user := range users {
+		err := models.DeleteUserData(user, ctx)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		if user.Profile.Avatar != "" {
+			if err := os.Remove(filepath.Join(avatarDir, filepath.Base(user.Profile.Avatar))); err != nil && !os.IsNotExist(err) {
+				log.Println(err)
+			}
+		}
+
+		exportStore := models.DataExportStore{Collection: db.DataExportsCollection()}
+		if exports, err := exportStore.FindExportsByUserID(user.ID, ctx); err != nil {
+			log.Println(err)
+		} else {
+			deleteDataExports(ctx, exports)
+		}
+
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// RunAccountPurge deletes the accounts whose grace period has ended every interval until the context is cancelled.
+func RunAccountPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := PurgeDeletedAccounts(ctx, 50)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			if deleted > 0 {
+				log.Printf("Deleted %d accounts after their grace period", deleted)
+			}
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"infy/db"
 	"infy/models"
+	"infy/tmdb"
 	"log"
 	"net/http"
 
@@ -11,18 +13,34 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// GetReportedPosts fetches a list of reported posts up to a specified limit.
+// GetReportedPosts fetches a page of reported posts, most reported first.
 func GetReportedPosts(c *gin.Context) {
-	limit := int64(20)
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
 
-	reportedPosts, err := models.FindReportedPosts(c.Request.Context(), limit)
+	reportedPosts, next, err := models.FindReportedPosts(c.Request.Context(), page)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "An error occurred"})
+		respondWithListError(c, err, "An error occurred")
+		return
+	}
+
+	posts := make([]*models.Post, 0, len(reportedPosts))
+	for _, reportedPost := range reportedPosts {
+		if reportedPost.Post != nil {
+			posts = append(posts, reportedPost.Post)
+		}
+	}
+
+	authors := models.AuthorLoader{Collection: db.UsersCollection()}
+	if err := authors.LoadPostAuthors(posts, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
 		log.Println(err)
 		return
 	}
 
-	c.JSON(200, reportedPosts)
+	c.JSON(200, pageResponse("reports", reportedPosts, next))
 }
 
 // DeleteReportedPost allows an admin to delete a post identified by its ID.
@@ -74,26 +92,75 @@ func DeleteReportedPost(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Post deleted successfully"})
 }
 
-// ToggleAdminStatus allows a superadmin to toggle the admin status of a user.
-func ToggleAdminStatus(c *gin.Context) {
-	userID := c.Param("id")
+// SetUserRole changes the role of a user. Staff can only manage users and roles ranked below their own,
+// and never their own role.
+func SetUserRole(c *gin.Context) {
+	actor := c.MustGet("user").(*models.User)
 
-	err := models.ToggleAdmin(userID, c.Request.Context())
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	target, err := models.FindUserByID(c.Param("id"), c.Request.Context())
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to update admin status"})
+		if errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
 		log.Println(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Admin status updated successfully"})
+	switch err := models.CanAssignRole(actor, target, request.Role); {
+	case errors.Is(err, models.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	case err != nil:
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to change this user's role"})
+		return
+	}
+
+	if err := models.SetRole(target.ID, target.Role, request.Role, c.Request.Context()); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// The role changed since it was checked, so the change has to be checked again
+			c.JSON(http.StatusConflict, gin.H{"error": "The user's role was changed in the meantime"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "role": request.Role})
 }
 
-// GetUsers retrieves all users from the database.
+// GetUsers retrieves a page of all users from the database.
 func GetUsers(c *gin.Context) {
-	users, err := models.GetUsers()
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+
+	users, next, err := models.GetUsers(c.Request.Context(), page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		respondWithListError(c, err, "Failed to retrieve users")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"users": users})
+	c.JSON(http.StatusOK, pageResponse("users", users, next))
+}
+
+// GetTMDBCacheStats returns the hit and miss counters of the TMDB response cache.
+func GetTMDBCacheStats(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, client.CacheStats())
+	}
 }
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"errors"
+	"infy/db"
+	"infy/models"
+	"infy/utils"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetAPITokens lists the active personal access tokens of the authenticated user.
+func GetAPITokens(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	tokenStore := models.APITokenStore{Collection: db.APITokensCollection()}
+	tokens, err := tokenStore.FindActiveTokensByUserID(user.ID, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve tokens"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// CreateAPIToken creates a personal access token for the authenticated user. The token is only returned this once.
+func CreateAPIToken(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var request struct {
+		Name          string   `json:"name" binding:"required,max=100"`
+		Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=read write admin"`
+		ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // Omit for a token that never expires
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	if slices.Contains(request.Scopes, models.ScopeAdmin) && !user.IsStaff() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only staff accounts can create tokens with the admin scope"})
+		return
+	}
+
+	secret, err := utils.NewToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		log.Println(err)
+		return
+	}
+	token := models.APITokenPrefix + secret
+
+	ttl := time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	apiToken := models.NewAPIToken(user.ID, request.Name, utils.HashToken(token), request.Scopes, ttl)
+
+	tokenStore := models.APITokenStore{Collection: db.APITokensCollection()}
+	if err := tokenStore.Save(apiToken, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": token, "details": apiToken})
+}
+
+// RevokeAPIToken revokes one of the authenticated user's personal access tokens.
+func RevokeAPIToken(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	if _, err := primitive.ObjectIDFromHex(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	tokenStore := models.APITokenStore{Collection: db.APITokensCollection()}
+	err := tokenStore.RevokeToken(c.Param("id"), user.ID, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke token"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Token revoked"})
+}
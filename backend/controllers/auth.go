@@ -2,9 +2,13 @@ package controllers
 
 import (
 	"errors"
+	"infy/db"
+	"infy/mailer"
 	"infy/models"
 	"infy/utils"
+	"infy/validation"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,110 +30,196 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Retrieve the user by email
+	// Retrieve the user by email, unknown emails still count towards the lockout
 	user, err := models.FindUserByEmail(login.Email, c.Request.Context())
-	if err != nil {
-		// Handle not found error specifically
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			c.JSON(401, gin.H{"error": "Invalid email or password"})
-			return
-		}
-
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
 		c.JSON(500, gin.H{"error": "An error occurred"})
 		log.Println(err)
 		return
 	}
 
-	// Verify the password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(login.Password))
+	// Refuse to check the password while the account or the client IP is locked
+	retryAfter, err := loginRetryAfter(c, login.Email)
 	if err != nil {
-		c.JSON(401, gin.H{"error": "Invalid email or password"})
+		c.JSON(500, gin.H{"error": "An error occurred"})
+		log.Println(err)
 		return
 	}
 
-	// Generate a JWT token and set it as a cookie
-	expTime := time.Now().Add(24 * time.Hour)
-	token, err := user.GetJwtToken(expTime)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Could not generate token"})
-		log.Println(err)
+	if retryAfter > 0 {
+		if _, err := recordLoginFailure(c, login.Email, user, models.LoginFailureLocked); err != nil {
+			log.Println(err)
+		}
+
+		tooManyLoginAttempts(c, retryAfter)
 		return
 	}
 
-	c.SetCookie("token", token, int(expTime.Unix()), "/", utils.GetEnv("SITE_DOMAIN", "localhost"), utils.IsProd(), true)
-	c.JSON(200, gin.H{"success": "Logged in"})
-}
+	// Verify the password
+	if user == nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(login.Password)) != nil {
+		retryAfter, err := recordLoginFailure(c, login.Email, user, models.LoginFailureInvalidPassword)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
 
-// Signup creates a new user account with the provided details after verifying that the user does not already exist.
-func Signup(c *gin.Context) {
-	var signup struct {
-		Email           string `json:"email" binding:"required"`
-		Username        string `json:"username" binding:"required"`
-		Password        string `json:"password" binding:"required"`
-		ConfirmPassword string `json:"confirm_password" binding:"required"`
-		FirstName       string `json:"first_name" binding:"required"`
-		LastName        string `json:"last_name" binding:"required"`
-		DateOfBirth     string `json:"date_of_birth" binding:"required"`
-	}
+		if retryAfter > 0 {
+			tooManyLoginAttempts(c, retryAfter)
+			return
+		}
 
-	// Bind and validate request body
-	if err := c.ShouldBindJSON(&signup); err != nil {
-		c.JSON(500, gin.H{"error": "Invalid request data"})
-		log.Println(err)
+		c.JSON(401, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	// Ensure passwords match
-	if signup.Password != signup.ConfirmPassword {
-		c.JSON(400, gin.H{"error": "Passwords do not match"})
-		return
-	}
+	// Users with two-factor authentication finish logging in through /auth/login/2fa
+	if user.TwoFactor.Enabled {
+		challenge, err := newTwoFactorChallenge(user)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Could not generate token"})
+			log.Println(err)
+			return
+		}
 
-	// Check if the user already exists
-	user, _ := models.FindUserByEmail(signup.Email, c.Request.Context())
-	if user != nil {
-		c.JSON(400, gin.H{"error": "User already exists"})
+		c.JSON(200, gin.H{"two_factor_required": true, "challenge": challenge})
 		return
 	}
 
-	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(signup.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to hash password"})
-		log.Println(err)
-		return
-	}
+	// The failures are only cleared once the login is complete, so they keep counting through the second step
+	clearLoginFailures(c, login.Email)
 
-	// Parse the date of birth and create the user profile
-	dateOfBirth, err := time.Parse("2006-01-02", signup.DateOfBirth)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Invalid date format"})
+	// Start a session and set the access and refresh tokens as cookies
+	if err := startSession(c, user); err != nil {
+		c.JSON(500, gin.H{"error": "Could not generate token"})
 		log.Println(err)
 		return
 	}
 
-	profile := models.NewProfile(signup.FirstName, signup.LastName, dateOfBirth, models.NewPreferences())
-	user = models.NewUser(signup.Username, signup.Email, string(hashedPassword), profile)
+	// Staff can still log in to enroll, but the admin routes stay closed until they do
+	c.JSON(200, gin.H{"success": "Logged in", "two_factor_setup_required": user.IsStaff() && adminTwoFactorRequired()})
+}
 
-	// Save the new user and handle potential errors
-	err = user.Save(c.Request.Context())
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create user"})
-		log.Println(err)
-		return
+// Signup creates a new user account with the provided details after validating them and verifying that the
+// email and username are not taken. Problems are reported per field.
+func Signup(mail mailer.Mailer, policy *validation.PasswordPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var signup struct {
+			Email           string `json:"email"`
+			Username        string `json:"username"`
+			Password        string `json:"password"`
+			ConfirmPassword string `json:"confirm_password"`
+			FirstName       string `json:"first_name"`
+			LastName        string `json:"last_name"`
+			DateOfBirth     string `json:"date_of_birth"`
+		}
+
+		// Bind the request body, the fields are validated below so each problem can be reported
+		if err := c.ShouldBindJSON(&signup); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request data"})
+			log.Println(err)
+			return
+		}
+
+		signup.Email = strings.TrimSpace(signup.Email)
+		signup.Username = strings.TrimSpace(signup.Username)
+
+		fields := validation.FieldErrors{}
+		fields.Required("email", signup.Email)
+		fields.Required("username", signup.Username)
+		fields.Required("password", signup.Password)
+		fields.Required("first_name", signup.FirstName)
+		fields.Required("last_name", signup.LastName)
+		fields.Required("date_of_birth", signup.DateOfBirth)
+
+		fields.Add("email", validation.Email(signup.Email))
+		fields.Add("username", validation.Username(signup.Username))
+		fields.Add("password", policy.Check(signup.Password, signup.Username, signup.Email))
+
+		// Ensure passwords match
+		if signup.Password != signup.ConfirmPassword {
+			fields.Add("confirm_password", "Passwords do not match")
+		}
+
+		// Parse the date of birth and check the minimum age
+		dateOfBirth, err := time.Parse("2006-01-02", signup.DateOfBirth)
+		if err != nil {
+			fields.Add("date_of_birth", "Date of birth must be formatted as YYYY-MM-DD")
+		} else {
+			fields.Add("date_of_birth", validation.DateOfBirth(dateOfBirth, time.Now(), utils.GetEnvInt("MIN_SIGNUP_AGE", validation.DefaultMinAge)))
+		}
+
+		// Only look the user up once the input is valid, the lookups ignore case like the unique indexes
+		if fields.Empty() {
+			if err := checkUserAvailable(c, signup.Email, signup.Username, fields); err != nil {
+				c.JSON(500, gin.H{"error": "An error occurred"})
+				log.Println(err)
+				return
+			}
+		}
+
+		if !fields.Empty() {
+			c.JSON(400, gin.H{"error": "Invalid signup data", "fields": fields})
+			return
+		}
+
+		// Hash the password
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(signup.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to hash password"})
+			log.Println(err)
+			return
+		}
+
+		profile := models.NewProfile(signup.FirstName, signup.LastName, dateOfBirth, models.NewPreferences())
+		user := models.NewUser(signup.Username, signup.Email, string(hashedPassword), profile)
+
+		// Save the new user and handle potential errors
+		err = user.Save(c.Request.Context())
+		if field := models.DuplicateUserField(err); field != "" {
+			fields.Add(field, "This "+field+" is already taken")
+			c.JSON(400, gin.H{"error": "Invalid signup data", "fields": fields})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to create user"})
+			log.Println(err)
+			return
+		}
+
+		// The account works right away, failing to send the email only means the user has to request a new one
+		if err := sendVerificationEmail(c, mail, user); err != nil {
+			log.Println(err)
+		}
+
+		// Start a session and set the access and refresh tokens as cookies
+		if err := startSession(c, user); err != nil {
+			c.JSON(500, gin.H{"error": "Could not generate token"})
+			log.Println(err)
+			return
+		}
+
+		c.JSON(200, gin.H{"success": "User created"})
 	}
+}
 
-	// Generate a JWT token and set it as a cookie
-	expTime := time.Now().Add(24 * time.Hour)
-	token, err := user.GetJwtToken(expTime)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Could not generate token"})
-		log.Println(err)
-		return
+// checkUserAvailable adds field errors for an email or username that another user already has.
+func checkUserAvailable(c *gin.Context, email, username string, fields validation.FieldErrors) error {
+	_, err := models.FindUserByEmail(email, c.Request.Context())
+	if err == nil {
+		fields.Add("email", "This email is already taken")
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	_, err = models.FindUserByUsername(username, c.Request.Context())
+	if err == nil {
+		fields.Add("username", "This username is already taken")
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
 	}
 
-	c.SetCookie("token", token, int(expTime.Unix()), "/", utils.GetEnv("SITE_DOMAIN", "localhost"), utils.IsProd(), true)
-	c.JSON(200, gin.H{"success": "User created"})
+	return nil
 }
 
 // User retrieves and displays the current authenticated user's details.
@@ -140,11 +230,26 @@ func User(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{"user": user.(*models.User)})
+	// The permissions let clients show only the actions the user's role allows
+	c.JSON(200, gin.H{"user": user.(*models.User), "permissions": user.(*models.User).Permissions()})
 }
 
-// Logout terminates the user session by clearing the authentication token cookie.
+// Logout terminates the user session by revoking it on the server and clearing the authentication cookies.
 func Logout(c *gin.Context) {
-	c.SetCookie("token", "", -1, "/", utils.GetEnv("SITE_DOMAIN", "localhost"), utils.IsProd(), true)
+	// The refresh token identifies the session even when the access token has already expired
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil && refreshToken != "" {
+		sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+		session, err := sessionStore.FindSessionByTokenHash(utils.HashToken(refreshToken), c.Request.Context())
+		if err == nil {
+			err = sessionStore.RevokeSession(session.ID.Hex(), session.UserID, c.Request.Context())
+		}
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(500, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+	}
+
+	clearAuthCookies(c)
 	c.JSON(200, gin.H{"success": "Logged out"})
 }
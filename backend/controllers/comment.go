@@ -1,20 +1,25 @@
 package controllers
 
 import (
+	"errors"
+	"infy/db"
 	"infy/models"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// CreateComment processes the incoming request to create a new comment on a post.
+// CreateComment processes the incoming request to create a new comment on a post, or a reply to a comment if a
+// parent ID is given.
 func CreateComment(c *gin.Context) {
 	var comment struct {
-		PostID  string `json:"post_id" binding:"required"`
-		Content string `json:"content" binding:"required"`
+		PostID   string `json:"post_id" binding:"required"`
+		ParentID string `json:"parent_id"`
+		Content  string `json:"content" binding:"required"`
 	}
 
 	// Bind JSON payload to the struct and handle binding errors
@@ -38,9 +43,42 @@ func CreateComment(c *gin.Context) {
 		return
 	}
 
-	// Instantiate and save new comment
 	newComment := models.NewComment(postID, user.(*models.User), comment.Content)
+
+	// Replies are nested under the comment they reply to, which has to be on the same post
+	if comment.ParentID != "" {
+		parent, err := models.FindCommentsByID(comment.ParentID, c.Request.Context())
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				c.JSON(400, gin.H{"error": "Invalid parent comment ID"})
+				return
+			}
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				c.JSON(404, gin.H{"error": "Parent comment not found"})
+				return
+			}
+			c.JSON(500, gin.H{"error": "Failed to save comment"})
+			log.Println(err)
+			return
+		}
+
+		if parent.PostID != postID {
+			c.JSON(400, gin.H{"error": "Parent comment is on another post"})
+			return
+		}
+
+		if newComment, err = models.NewReply(parent, user.(*models.User), comment.Content); err != nil {
+			c.JSON(400, gin.H{"error": "Comments can only be nested " + strconv.Itoa(models.MaxCommentDepth) + " replies deep"})
+			return
+		}
+	}
+
+	// Save the new comment
 	if err := newComment.Save(c.Request.Context()); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(404, gin.H{"error": "Parent comment not found"})
+			return
+		}
 		c.JSON(500, gin.H{"error": "Failed to save comment"})
 		log.Println(err)
 		return
@@ -49,6 +87,50 @@ func CreateComment(c *gin.Context) {
 	c.JSON(200, newComment)
 }
 
+// GetCommentReplies retrieves a page of the replies to a comment, each with its first replies.
+func GetCommentReplies(c *gin.Context) {
+	commentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+
+	viewer := viewerID(c)
+	replies, next, err := models.FindReplies(commentID, viewer, c.Request.Context(), page)
+	if err != nil {
+		respondWithListError(c, err, "Failed to retrieve replies")
+		return
+	}
+
+	authors := models.AuthorLoader{Collection: db.UsersCollection()}
+	if err := loadCommentThreads(c, replies, viewer, &authors); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve replies"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse("replies", replies, next))
+}
+
+// loadCommentThreads loads the first replies of the comments and the authors of the comments and their replies.
+func loadCommentThreads(c *gin.Context, comments []*models.Comment, viewer primitive.ObjectID, authors *models.AuthorLoader) error {
+	if err := models.LoadReplies(comments, viewer, c.Request.Context()); err != nil {
+		return err
+	}
+
+	all := append([]*models.Comment{}, comments...)
+	for _, comment := range comments {
+		all = append(all, comment.Replies...)
+	}
+
+	return authors.LoadCommentAuthors(all, c.Request.Context())
+}
+
 // UpdateComment modifies an existing comment based on the comment ID provided in the URL.
 func UpdateComment(c *gin.Context) {
 	var comment struct {
@@ -100,7 +182,7 @@ func DeleteComment(c *gin.Context) {
 	}
 
 	// Attempt to delete the comment
-	err := models.DeleteUserComment(c.Param("id"), user.(*models.User).ID, c.Request.Context())
+	err := models.DeleteUserComment(c.Param("id"), user.(*models.User), c.Request.Context())
 	if err != nil {
 		// Handle no documents and invalid ID errors specifically
 		if err == mongo.ErrNoDocuments {
@@ -119,47 +201,3 @@ func DeleteComment(c *gin.Context) {
 
 	c.JSON(200, gin.H{"message": "Comment deleted successfully"})
 }
-
-// LikeComment toggles the 'like' status of a comment for the authenticated user.
-func LikeComment(c *gin.Context) {
-	commentID := c.Param("commentId")
-	userID, ok := getUserIDFromContext(c)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	if err := models.ToggleLikeOnComment(commentID, userID.Hex(), true); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to like comment"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": "Comment liked successfully"})
-}
-
-// DislikeComment toggles the 'dislike' status of a comment for the authenticated user.
-func DislikeComment(c *gin.Context) {
-	commentID := c.Param("commentId")
-	userID, ok := getUserIDFromContext(c)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	if err := models.ToggleLikeOnComment(commentID, userID.Hex(), false); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dislike comment"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": "Comment disliked successfully"})
-}
-
-// getUserIDFromContext extracts the user ID from the authenticated user context.
-func getUserIDFromContext(c *gin.Context) (primitive.ObjectID, bool) {
-	user, exists := c.Get("user")
-	if !exists {
-		return primitive.NilObjectID, false
-	}
-	if userModel, ok := user.(*models.User); ok {
-		return userModel.ID, true
-	}
-	return primitive.NilObjectID, false
-}
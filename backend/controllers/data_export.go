@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"infy/db"
+	"infy/export"
+	"infy/models"
+	"infy/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultExportTTL   = 7 * 24 * time.Hour
+	exportBuildTimeout = 10 * time.Minute
+	exportLinkTTL      = 15 * time.Minute
+
+	// exportCooldown is how long a user has to wait before requesting another export
+	exportCooldown = time.Hour
+
+	// exportLinkAudience keeps download tokens from being accepted anywhere else
+	exportLinkAudience = "export"
+)
+
+// exportDir returns the directory the export archives are written to.
+func exportDir() string {
+	return utils.GetEnv("EXPORT_DIR", "exports")
+}
+
+// newExportLink returns a download link for the export's archive that expires after a short while, or when the
+// archive does, whichever comes first.
+func newExportLink(dataExport *models.DataExport) (string, error) {
+	expiresAt := time.Now().Add(exportLinkTTL)
+	if dataExport.ExpiresAt.Before(expiresAt) {
+		expiresAt = dataExport.ExpiresAt
+	}
+
+	claims := jwt.RegisteredClaims{
+		Subject:   dataExport.ID.Hex(),
+		Audience:  jwt.ClaimStrings{exportLinkAudience},
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(utils.GetEnv("JWT_SECRET_KEY", "")))
+	if err != nil {
+		return "", err
+	}
+
+	return "/profile/user/export/download?token=" + url.QueryEscape(token), nil
+}
+
+// parseExportLink returns the ID of the export a download token was issued for.
+func parseExportLink(token string) (primitive.ObjectID, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(utils.GetEnv("JWT_SECRET_KEY", "")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(exportLinkAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return primitive.ObjectIDFromHex(claims.Subject)
+}
+
+// exportResponse responds with the export's status and a download link once the archive is ready.
+func exportResponse(c *gin.Context, status int, dataExport *models.DataExport) {
+	response := gin.H{"export": dataExport}
+
+	if dataExport.Status == models.ExportStatusReady {
+		link, err := newExportLink(dataExport)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+		response["download_url"] = link
+	}
+
+	c.JSON(status, response)
+}
+
+// GetDataExport returns the status of the authenticated user's latest data export, starting a new one in the
+// background if there is none, it expired or failed, or ?refresh=true asks for a new one.
+func GetDataExport(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	exportStore := models.DataExportStore{Collection: db.DataExportsCollection()}
+
+	latest, err := exportStore.FindLatestExport(user.ID, c.Request.Context())
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	// An export that never finished would otherwise keep the user from starting another until it expires
+	if latest != nil && latest.IsStalled(exportBuildTimeout) {
+		if err := exportStore.CompleteExport(latest.ID, models.ExportStatusFailed, "", c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+		latest.Status = models.ExportStatusFailed
+	}
+
+	if latest != nil && !latest.IsExpired() && latest.Status != models.ExportStatusFailed {
+		// Exports are expensive, so a running or recent one is returned instead of starting another
+		recent := time.Since(latest.CreatedAt) < exportCooldown
+		if latest.Status == models.ExportStatusPending || recent || c.Query("refresh") != "true" {
+			exportResponse(c, http.StatusOK, latest)
+			return
+		}
+	}
+
+	dataExport := models.NewDataExport(user.ID, utils.GetEnvDuration("EXPORT_TTL", defaultExportTTL))
+	if err := exportStore.Save(dataExport, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start export"})
+		log.Println(err)
+		return
+	}
+
+	go buildDataExport(dataExport, user)
+
+	exportResponse(c, http.StatusAccepted, dataExport)
+}
+
+// buildDataExport writes the archive of the user's data and records whether it succeeded.
+func buildDataExport(dataExport *models.DataExport, user *models.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportBuildTimeout)
+	defer cancel()
+
+	exportStore := models.DataExportStore{Collection: db.DataExportsCollection()}
+
+	path, err := writeDataExport(ctx, dataExport, user)
+	if err != nil {
+		log.Println(err)
+		if err := exportStore.CompleteExport(dataExport.ID, models.ExportStatusFailed, "", ctx); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	if err := exportStore.CompleteExport(dataExport.ID, models.ExportStatusReady, path, ctx); err != nil {
+		log.Println(err)
+	}
+}
+
+// writeDataExport collects the user's data and writes the archive, returning its path.
+func writeDataExport(ctx context.Context, dataExport *models.DataExport, user *models.User) (string, error) {
+	data, err := export.Collect(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(exportDir(), 0o700); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(exportDir(), dataExport.ID.Hex()+".zip")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var avatarPath string
+	if user.Profile.Avatar != "" {
+		avatarPath = filepath.Join(avatarDir, filepath.Base(user.Profile.Avatar))
+	}
+
+	if err := export.WriteArchive(file, data, avatarPath); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	return path, nil
+}
+
+// DownloadDataExport sends the archive of a data export. The link's token is the authorization,
+// so it works for plain browser downloads.
+func DownloadDataExport(c *gin.Context) {
+	exportID, err := parseExportLink(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Download link is invalid or expired"})
+		return
+	}
+
+	exportStore := models.DataExportStore{Collection: db.DataExportsCollection()}
+	dataExport, err := exportStore.FindExportByID(exportID, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	if dataExport.Status != models.ExportStatusReady || dataExport.IsExpired() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+
+	c.FileAttachment(dataExport.FilePath, "infy-export-"+dataExport.CreatedAt.Format("2006-01-02")+".zip")
+}
+
+// deleteDataExports removes the archives and records of the given exports.
+func deleteDataExports(ctx context.Context, exports []*models.DataExport) int {
+	exportStore := models.DataExportStore{Collection: db.DataExportsCollection()}
+
+	deleted := 0
+	for _, dataExport := range exports {
+		if dataExport.FilePath != "" {
+			if err := os.Remove(dataExport.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Println(err)
+				continue
+			}
+		}
+
+		if err := exportStore.DeleteExport(dataExport.ID, ctx); err != nil {
+			log.Println(err)
+			continue
+		}
+		deleted++
+	}
+
+	return deleted
+}
+
+// RunExportCleanup deletes expired export archives every interval until the context is cancelled.
+func RunExportCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	exportStore := models.DataExportStore{Collection: db.DataExportsCollection()}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := exportStore.FindExpiredExports(time.Now(), 100, ctx)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			if deleted := deleteDataExports(ctx, expired); deleted > 0 {
+				log.Printf("Deleted %d expired data exports", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"errors"
+	"infy/db"
+	"infy/models"
+	"infy/utils"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// loginAttemptsLimit is how many failed logins are shown on the account page
+const loginAttemptsLimit = 50
+
+// accountLockoutPolicy returns the lockout policy of a single account.
+func accountLockoutPolicy() models.LockoutPolicy {
+	return models.LockoutPolicy{
+		MaxAttempts:  utils.GetEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		BaseDuration: utils.GetEnvDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		MaxDuration:  utils.GetEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		Window:       utils.GetEnvDuration("LOGIN_FAILURE_WINDOW", 24*time.Hour),
+	}
+}
+
+// ipLockoutPolicy returns the lockout policy of a client IP, which allows more failures since IPs can be shared.
+func ipLockoutPolicy() models.LockoutPolicy {
+	policy := accountLockoutPolicy()
+	policy.MaxAttempts = utils.GetEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20)
+
+	return policy
+}
+
+// loginRetryAfter returns how long the account and the client IP stay locked, zero if neither is.
+func loginRetryAfter(c *gin.Context, email string) (time.Duration, error) {
+	lockStore := models.LoginLockStore{Collection: db.LoginLocksCollection()}
+
+	var retryAfter time.Duration
+	for _, key := range []string{models.AccountLockKey(email), models.IPLockKey(c.ClientIP())} {
+		lockedUntil, err := lockStore.LockedUntil(key, c.Request.Context())
+		if err != nil {
+			return 0, err
+		}
+
+		retryAfter = max(retryAfter, time.Until(lockedUntil))
+	}
+
+	return retryAfter, nil
+}
+
+// recordLoginFailure counts a failed login against the account and the client IP and returns how long
+// they are now locked. The failure is also added to the user's audit log if the account exists.
+func recordLoginFailure(c *gin.Context, email string, user *models.User, reason string) (time.Duration, error) {
+	if user != nil {
+		attemptStore := models.LoginAttemptStore{Collection: db.LoginAttemptsCollection()}
+		attempt := models.NewLoginAttempt(user.ID, c.ClientIP(), c.Request.UserAgent(), reason)
+		if err := attemptStore.Save(attempt, c.Request.Context()); err != nil {
+			log.Println(err)
+		}
+	}
+
+	// Attempts made while locked are only audited, otherwise waiting out the lock would never end it
+	if reason == models.LoginFailureLocked {
+		return 0, nil
+	}
+
+	lockStore := models.LoginLockStore{Collection: db.LoginLocksCollection()}
+
+	accountLock, err := lockStore.RecordFailure(models.AccountLockKey(email), accountLockoutPolicy(), c.Request.Context())
+	if err != nil {
+		return 0, err
+	}
+
+	ipLock, err := lockStore.RecordFailure(models.IPLockKey(c.ClientIP()), ipLockoutPolicy(), c.Request.Context())
+	if err != nil {
+		return 0, err
+	}
+
+	return max(time.Until(accountLock.LockedUntil), time.Until(ipLock.LockedUntil), 0), nil
+}
+
+// clearLoginFailures forgets the failed logins of the account once the user has logged in.
+// The client IP keeps its failures, otherwise logging in to one account would allow guessing others.
+func clearLoginFailures(c *gin.Context, email string) {
+	lockStore := models.LoginLockStore{Collection: db.LoginLocksCollection()}
+	if err := lockStore.Reset(models.AccountLockKey(email), c.Request.Context()); err != nil {
+		log.Println(err)
+	}
+}
+
+// tooManyLoginAttempts responds that logging in is locked and when it can be retried.
+func tooManyLoginAttempts(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, please try again later", "retry_after": seconds})
+}
+
+// GetLoginAttempts lists the latest failed logins of the authenticated user's account.
+func GetLoginAttempts(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	attemptStore := models.LoginAttemptStore{Collection: db.LoginAttemptsCollection()}
+	attempts, err := attemptStore.FindAttemptsByUserID(user.ID, loginAttemptsLimit, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attempts": attempts})
+}
+
+// UnlockUser allows an admin to clear the failed logins and lockout of a user's account.
+func UnlockUser(c *gin.Context) {
+	user, err := models.FindUserByID(c.Param("id"), c.Request.Context())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	lockStore := models.LoginLockStore{Collection: db.LoginLocksCollection()}
+	if err := lockStore.Reset(models.AccountLockKey(user.Email), c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
@@ -1,143 +1,367 @@
 package controllers
 
 import (
-	"infy/api"
+	"errors"
+	"infy/tmdb"
+	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// searchQuery holds the optional paging and filter parameters shared by the search endpoints.
+type searchQuery struct {
+	Page         int    `form:"page" binding:"omitempty,min=1,max=500"`
+	Year         int    `form:"year" binding:"omitempty,min=1870,max=2100"`
+	IncludeAdult bool   `form:"include_adult"`
+	Language     string `form:"language" binding:"omitempty,bcp47_language_tag"`
+	Region       string `form:"region" binding:"omitempty,iso3166_1_alpha2"`
+}
+
+// options converts the query parameters to the options of the TMDB client.
+func (q searchQuery) options() tmdb.SearchOptions {
+	return tmdb.SearchOptions{Page: q.Page, Year: q.Year, IncludeAdult: q.IncludeAdult, Language: q.Language, Region: q.Region}
+}
+
 // SearchMovies searches for movies based on a title query parameter.
-func SearchMovies(c *gin.Context) {
-	query := c.Query("title") // Retrieve the movie title from the query parameters.
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Movie title is required"}) // Validate the presence of the title query.
-		return
+func SearchMovies(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Query("title") // Retrieve the movie title from the query parameters.
+		if query == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Movie title is required"}) // Validate the presence of the title query.
+			return
+		}
+
+		var params searchQuery
+		if err := c.ShouldBindQuery(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters"}) // Validate the optional filters.
+			return
+		}
+
+		results, err := client.SearchMovies(c.Request.Context(), query, params.options()) // Call to external API to search for movies by title.
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to search movies") // Handle errors from the movie search API.
+			return
+		}
+
+		c.JSON(http.StatusOK, results) // Return the search results as a JSON response.
 	}
+}
 
-	results, err := api.SearchMovies(query) // Call to external API to search for movies by title.
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search movies"}) // Handle errors from the movie search API.
-		return
+// SearchPeople searches for people based on a name query parameter.
+func SearchPeople(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Query("name")
+		if query == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Actor name is required"})
+			return
+		}
+
+		var params searchQuery
+		if err := c.ShouldBindQuery(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters"})
+			return
+		}
+
+		results, err := client.SearchActors(c.Request.Context(), query, params.options())
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to search actors")
+			return
+		}
+
+		c.JSON(http.StatusOK, results)
 	}
+}
+
+// GetPersonCredits returns the movie and TV cast and crew credits of a person, optionally filtered by department.
+func GetPersonCredits(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		personID := c.Param("personID")
+		department := c.Query("department") // Acting, Directing, Writing, Sound, etc.
 
-	c.JSON(http.StatusOK, results) // Return the search results as a JSON response.
+		credits, err := client.GetPersonCredits(c.Request.Context(), personID, department)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch person credits")
+			return
+		}
+
+		c.JSON(http.StatusOK, credits)
+	}
+}
+
+// discoverQuery holds the filter parameters of the discover endpoint.
+type discoverQuery struct {
+	Page             int     `form:"page" binding:"omitempty,min=1,max=500"`
+	Genres           string  `form:"genres"` // Comma separated genre IDs
+	YearFrom         int     `form:"year_from" binding:"omitempty,min=1870,max=2100"`
+	YearTo           int     `form:"year_to" binding:"omitempty,min=1870,max=2100"`
+	MinVoteAverage   float64 `form:"min_vote_average" binding:"omitempty,min=0,max=10"`
+	RuntimeMin       int     `form:"runtime_min" binding:"omitempty,min=0"`
+	RuntimeMax       int     `form:"runtime_max" binding:"omitempty,min=0"`
+	SortBy           string  `form:"sort_by"`
+	OriginalLanguage string  `form:"original_language" binding:"omitempty,iso639_1"`
 }
 
-func SearchPeople(c *gin.Context) {
-	query := c.Query("name")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Actor name is required"})
-		return
+// options validates the parameters that depend on each other and converts them to the options of the TMDB client.
+func (q discoverQuery) options() (tmdb.DiscoverOptions, error) {
+	opts := tmdb.DiscoverOptions{
+		Page:             q.Page,
+		YearFrom:         q.YearFrom,
+		YearTo:           q.YearTo,
+		MinVoteAverage:   q.MinVoteAverage,
+		RuntimeMin:       q.RuntimeMin,
+		RuntimeMax:       q.RuntimeMax,
+		SortBy:           q.SortBy,
+		OriginalLanguage: q.OriginalLanguage,
+	}
+
+	if q.Genres != "" {
+		for _, genre := range strings.Split(q.Genres, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(genre))
+			if err != nil || id <= 0 {
+				return opts, errors.New("genres must be a comma separated list of genre IDs")
+			}
+			opts.GenreIDs = append(opts.GenreIDs, id)
+		}
 	}
 
-	results, err := api.SearchActors(query)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search actors"})
-		return
+	if q.YearFrom > 0 && q.YearTo > 0 && q.YearFrom > q.YearTo {
+		return opts, errors.New("year_from must not be after year_to")
 	}
 
-	c.JSON(http.StatusOK, results)
+	if q.RuntimeMin > 0 && q.RuntimeMax > 0 && q.RuntimeMin > q.RuntimeMax {
+		return opts, errors.New("runtime_min must not be greater than runtime_max")
+	}
+
+	if q.SortBy != "" && !slices.Contains(tmdb.DiscoverSortOrders, q.SortBy) {
+		return opts, errors.New("unsupported sort_by value")
+	}
+
+	return opts, nil
 }
 
-// GetTrendingMovies fetches trending movies based on a specified time window.
-func GetTrendingMovies(c *gin.Context) {
-	timeWindow := c.Param("timeWindow") // Extract timeWindow from the URL parameter.
+// GetMovieGenres returns the list of movie genres.
+func GetMovieGenres(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		genres, err := client.GetMovieGenres(c.Request.Context())
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch movie genres")
+			return
+		}
 
-	trendingMovies, err := api.GetTrendingMovies(timeWindow) // Fetch trending movies from the API.
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending movies"}) // Handle potential API errors.
-		return
+		c.JSON(http.StatusOK, genres)
 	}
+}
 
-	c.JSON(http.StatusOK, trendingMovies) // Respond with the fetched trending movies data.
+// DiscoverMovies finds movies matching the genre, release year, rating, runtime and language filters.
+func DiscoverMovies(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var params discoverQuery
+		if err := c.ShouldBindQuery(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discover parameters"})
+			return
+		}
+
+		opts, err := params.options()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		results, err := client.DiscoverMovies(c.Request.Context(), opts)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to discover movies")
+			return
+		}
+
+		c.JSON(http.StatusOK, results)
+	}
 }
 
-// GetMovieDetails fetches details for a single movie identified by its ID.
-func GetMovieDetails(c *gin.Context) {
-	movieID := c.Param("movieID") // Extract the movie ID from URL parameters.
+// GetTrendingMovies fetches trending movies based on a specified time window.
+func GetTrendingMovies(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeWindow := c.Param("timeWindow") // Extract timeWindow from the URL parameter.
 
-	movieDetails, _, err := api.GetMovieDetails(movieID, false) // Fetch movie details from the API.
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get movie details"}) // Handle errors from the movie details fetch.
-		return
+		trendingMovies, err := client.GetTrendingMovies(c.Request.Context(), timeWindow) // Fetch trending movies from the API.
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch trending movies") // Handle potential API errors.
+			return
+		}
+
+		c.JSON(http.StatusOK, trendingMovies) // Respond with the fetched trending movies data.
 	}
+}
+
+// GetMovieDetails fetches details for a single movie identified by its ID.
+func GetMovieDetails(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID := c.Param("movieID") // Extract the movie ID from URL parameters.
+
+		movieDetails, err := client.GetMovieDetails(c.Request.Context(), movieID) // Fetch movie details from the API.
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to get movie details") // Handle errors from the movie details fetch.
+			return
+		}
 
-	c.JSON(http.StatusOK, movieDetails) // Return the movie details as a JSON response.
+		c.JSON(http.StatusOK, movieDetails) // Return the movie details as a JSON response.
+	}
 }
 
 // GetMovieCast retrieves the cast of a specific movie by its ID.
-func GetMovieCast(c *gin.Context) {
-	movieID := c.Param("movieID") // Extract the movie ID from URL parameters.
+func GetMovieCast(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID := c.Param("movieID") // Extract the movie ID from URL parameters.
 
-	cast, err := api.GetMovieCast(movieID) // Fetch movie cast from the API.
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch movie cast"}) // Handle errors during the fetch.
-		return
-	}
+		cast, err := client.GetMovieCast(c.Request.Context(), movieID) // Fetch movie cast from the API.
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch movie cast") // Handle errors during the fetch.
+			return
+		}
 
-	c.JSON(http.StatusOK, cast) // Respond with the movie cast.
+		c.JSON(http.StatusOK, cast) // Respond with the movie cast.
+	}
 }
 
 // GetMovieReviews fetches reviews for a specific movie by its ID.
-func GetMovieReviews(c *gin.Context) {
-	movieID := c.Param("movieID") // Extract the movie ID from URL parameters.
+func GetMovieReviews(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID := c.Param("movieID") // Extract the movie ID from URL parameters.
 
-	reviews, err := api.GetMovieReviews(movieID) // Fetch reviews from the API.
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch movie reviews"}) // Handle errors during the fetch.
-		return
-	}
+		reviews, err := client.GetMovieReviews(c.Request.Context(), movieID) // Fetch reviews from the API.
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch movie reviews") // Handle errors during the fetch.
+			return
+		}
 
-	c.JSON(http.StatusOK, reviews) // Respond with the movie reviews.
+		c.JSON(http.StatusOK, reviews) // Respond with the movie reviews.
+	}
 }
 
 // GetSimilarMovies retrieves movies similar to a specified movie by its ID.
-func GetSimilarMovies(c *gin.Context) {
-	movieID := c.Param("movieID") // Extract the movie ID from URL parameters.
+func GetSimilarMovies(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID := c.Param("movieID") // Extract the movie ID from URL parameters.
 
-	similarMovies, err := api.GetSimilarMovies(movieID) // Fetch similar movies from the API.
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch similar movies"}) // Handle errors during the fetch.
-		return
-	}
+		similarMovies, err := client.GetSimilarMovies(c.Request.Context(), movieID) // Fetch similar movies from the API.
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch similar movies") // Handle errors during the fetch.
+			return
+		}
 
-	c.JSON(http.StatusOK, similarMovies) // Respond with the similar movies.
+		c.JSON(http.StatusOK, similarMovies) // Respond with the similar movies.
+	}
 }
 
-func GetActorDetails(c *gin.Context) {
-	actorID := c.Param("actorID")
+func GetActorDetails(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.Param("actorID")
 
-	actorDetails, err := api.GetActorDetails(actorID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch actor details"})
-		return
-	}
+		actorDetails, err := client.GetActorDetails(c.Request.Context(), actorID)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch actor details")
+			return
+		}
 
-	c.JSON(http.StatusOK, actorDetails)
+		c.JSON(http.StatusOK, actorDetails)
+	}
 }
 
 // For actor details page credits or possible future use for discover page
-func GetActorMovieCredits(c *gin.Context) {
-	actorID := c.Param("actorID")
+func GetActorMovieCredits(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.Param("actorID")
+
+		movieCredits, err := client.GetActorMovieCredits(c.Request.Context(), actorID)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch actor movie credits")
+			return
+		}
 
-	movieCredits, err := api.GetActorMovieCredits(actorID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch actor movie credits"})
-		return
+		c.JSON(http.StatusOK, movieCredits)
 	}
+}
 
-	c.JSON(http.StatusOK, movieCredits)
+func GetMovieTrailers(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID := c.Param("movieID")
+
+		trailers, err := client.GetMovieTrailers(c.Request.Context(), movieID)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch movie trailers")
+			return
+		}
+
+		c.JSON(http.StatusOK, trailers)
+	}
 }
 
-func GetMovieTrailers(c *gin.Context) {
-	movieID := c.Param("movieID")
+// providersQuery holds the region of the watch provider endpoints.
+type providersQuery struct {
+	Region string `form:"region" binding:"omitempty,iso3166_1_alpha2"`
+}
+
+// GetMovieWatchProviders lists where a movie can be streamed, rented or bought in a region.
+func GetMovieWatchProviders(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID := c.Param("movieID")
+
+		var params providersQuery
+		if err := c.ShouldBindQuery(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid region"})
+			return
+		}
 
-	trailers, err := api.GetMovieTrailers(movieID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch movie trailers", "details": err.Error()})
-		return
+		providers, err := client.GetMovieWatchProviders(c.Request.Context(), movieID, params.Region)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch movie watch providers")
+			return
+		}
+
+		c.JSON(http.StatusOK, providers)
+	}
+}
+
+// GetMovieProviders lists the streaming services available in a region so users can pick the ones they subscribe to.
+func GetMovieProviders(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var params providersQuery
+		if err := c.ShouldBindQuery(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid region"})
+			return
+		}
+
+		providers, err := client.GetMovieProviders(c.Request.Context(), params.Region)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch watch providers")
+			return
+		}
+
+		c.JSON(http.StatusOK, providers)
 	}
+}
 
-	c.JSON(http.StatusOK, trailers)
+// respondWithTMDBError maps an error from the TMDB client to a status code and a consistent JSON error body.
+func respondWithTMDBError(c *gin.Context, err error, message string) {
+	var apiErr *tmdb.Error
+
+	switch {
+	case errors.Is(err, tmdb.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "The requested resource was not found"})
+	case errors.Is(err, tmdb.ErrRateLimited):
+		// Pass on how long TMDB asked us to wait so clients can back off as well
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(apiErr.RetryAfter.Seconds())))
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Movie service is busy, please try again later"})
+	case errors.Is(err, tmdb.ErrUpstream):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Movie service is unavailable"})
+		log.Println(err)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		log.Println(err)
+	}
 }
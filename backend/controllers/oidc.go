@@ -0,0 +1,495 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"errors"
+	"infy/db"
+	"infy/models"
+	"infy/oidc"
+	"infy/utils"
+	"infy/validation"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/auth/oidc"
+	oidcStateTTL    = 10 * time.Minute
+	oidcSignupTTL   = 30 * time.Minute
+
+	// oidcSignupAudience keeps signup tokens from being accepted anywhere else
+	oidcSignupAudience = "oidc_signup"
+)
+
+// oidcState is kept in a signed cookie between redirecting to the provider and the callback.
+type oidcState struct {
+	jwt.RegisteredClaims
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	LinkUserID   string `json:"link_user_id,omitempty"` // Set when an existing user is linking the identity
+
+	// Set when a user without a password logs in again to confirm a sensitive change
+	ReauthUserID    string `json:"reauth_user_id,omitempty"`
+	ReauthSessionID string `json:"reauth_session_id,omitempty"`
+}
+
+// oidcSignup is the signed token that carries a new identity to the signup form, which asks for what the provider
+// does not share before the account is created.
+type oidcSignup struct {
+	jwt.RegisteredClaims
+	IdentityIssuer string      `json:"identity_issuer"`
+	Claims         oidc.Claims `json:"claims"`
+}
+
+// usernameReplacer matches the characters that are not allowed in generated usernames
+var usernameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_.]`)
+
+// redirectToProvider stores a new state in a cookie and redirects to the provider's login page. The purpose holds the
+// link or reauthentication fields of the state, the rest is generated.
+func redirectToProvider(c *gin.Context, provider *oidc.Provider, purpose oidcState) {
+	authURL, cookie, err := newOIDCState(c, provider, purpose)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Login provider is unavailable"})
+		log.Println(err)
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, cookie, int(oidcStateTTL.Seconds()), oidcCookiePath, utils.GetEnv("SITE_DOMAIN", "localhost"), utils.IsProd(), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// newOIDCState generates the state, nonce and PKCE verifier of a login and returns the provider's login URL and the signed state cookie.
+func newOIDCState(c *gin.Context, provider *oidc.Provider, purpose oidcState) (string, string, error) {
+	state, err := utils.NewToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := utils.NewToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	// A reauthentication must not be completed by the provider's own session
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier, purpose.ReauthSessionID != "")
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := purpose
+	claims.RegisteredClaims = jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcStateTTL))}
+	claims.State = state
+	claims.Nonce = nonce
+	claims.CodeVerifier = verifier
+
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(utils.GetEnv("JWT_SECRET_KEY", "")))
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, cookie, nil
+}
+
+// OIDCLogin starts logging in or signing up with the OpenID Connect provider.
+func OIDCLogin(provider *oidc.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		redirectToProvider(c, provider, oidcState{})
+	}
+}
+
+// OIDCLink starts linking an identity at the OpenID Connect provider to the authenticated user.
+func OIDCLink(provider *oidc.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
+		redirectToProvider(c, provider, oidcState{LinkUserID: user.ID.Hex()})
+	}
+}
+
+// OIDCReauthenticate starts logging in again at the OpenID Connect provider, which confirms sensitive changes to an
+// account without a password for the current session.
+func OIDCReauthenticate(provider *oidc.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
+		redirectToProvider(c, provider, oidcState{ReauthUserID: user.ID.Hex(), ReauthSessionID: c.GetString("session_id")})
+	}
+}
+
+// OIDCCallback completes the login or linking after the provider redirected back with an authorization code.
+func OIDCCallback(provider *oidc.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cookie, err := c.Cookie(oidcStateCookie)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Login session expired, please try again"})
+			return
+		}
+
+		// The state cookie is single use
+		c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, utils.GetEnv("SITE_DOMAIN", "localhost"), utils.IsProd(), true)
+
+		var state oidcState
+		_, err = jwt.ParseWithClaims(cookie, &state, func(token *jwt.Token) (interface{}, error) {
+			return []byte(utils.GetEnv("JWT_SECRET_KEY", "")), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		if err != nil || state.State == "" || c.Query("state") != state.State {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Login session expired, please try again"})
+			return
+		}
+
+		if errorCode := c.Query("error"); errorCode != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login was cancelled or denied", "details": errorCode})
+			return
+		}
+
+		claims, err := provider.Exchange(c.Request.Context(), c.Query("code"), state.CodeVerifier, state.Nonce)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Could not verify login"})
+			log.Println(err)
+			return
+		}
+
+		identity := models.Identity{Issuer: provider.Issuer(), Subject: claims.Subject, Email: claims.Email, LinkedAt: time.Now()}
+
+		linkedUser, err := models.FindUserByIdentity(identity.Issuer, identity.Subject, c.Request.Context())
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+
+		if state.LinkUserID != "" {
+			linkIdentity(c, identity, linkedUser, state.LinkUserID)
+			return
+		}
+
+		if state.ReauthSessionID != "" {
+			reauthenticate(c, claims, linkedUser, state)
+			return
+		}
+
+		// The provider does not share the date of birth, so new users enter it before their account is created
+		if linkedUser == nil {
+			token, err := newOIDCSignup(identity.Issuer, claims)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+				log.Println(err)
+				return
+			}
+
+			c.Redirect(http.StatusFound, utils.GetEnv("APP_URL", "http://localhost:5173")+"/signup/oidc?token="+url.QueryEscape(token))
+			return
+		}
+
+		user := linkedUser
+
+		// The provider only replaces the password, the second factor is still required
+		if user.TwoFactor.Enabled {
+			challenge, err := newTwoFactorChallenge(user)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+				log.Println(err)
+				return
+			}
+
+			c.Redirect(http.StatusFound, utils.GetEnv("APP_URL", "http://localhost:5173")+"/login/2fa?challenge="+url.QueryEscape(challenge))
+			return
+		}
+
+		if err := startSession(c, user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+			log.Println(err)
+			return
+		}
+
+		c.Redirect(http.StatusFound, utils.GetEnv("APP_URL", "http://localhost:5173"))
+	}
+}
+
+// linkIdentity links the identity to the user that started the linking unless another user already has it.
+func linkIdentity(c *gin.Context, identity models.Identity, linkedUser *models.User, userID string) {
+	if linkedUser != nil && linkedUser.ID.Hex() != userID {
+		c.JSON(http.StatusConflict, gin.H{"error": "This account is already linked to another user"})
+		return
+	}
+
+	user, err := models.FindUserByID(userID, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		log.Println(err)
+		return
+	}
+
+	err = models.LinkIdentity(user.ID, identity, c.Request.Context())
+	if models.IsDuplicateIdentity(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This account is already linked to another user"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not link account"})
+		log.Println(err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, utils.GetEnv("APP_URL", "http://localhost:5173")+"/profile")
+}
+
+// newOIDCSignup signs a token with the claims of an identity that is not linked to anyone yet.
+func newOIDCSignup(issuer string, claims *oidc.Claims) (string, error) {
+	signup := oidcSignup{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcSignupAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcSignupTTL)),
+		},
+		IdentityIssuer: issuer,
+		Claims:         *claims,
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, signup).SignedString([]byte(utils.GetEnv("JWT_SECRET_KEY", "")))
+}
+
+// parseOIDCSignup returns the identity issuer and claims a signup token was issued for.
+func parseOIDCSignup(token string) (*oidcSignup, error) {
+	var signup oidcSignup
+	_, err := jwt.ParseWithClaims(token, &signup, func(token *jwt.Token) (interface{}, error) {
+		return []byte(utils.GetEnv("JWT_SECRET_KEY", "")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(oidcSignupAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	return &signup, nil
+}
+
+// OIDCSignup creates the account of a new OpenID Connect identity with the signup token from the callback and the
+// date of birth, and logs the user in.
+func OIDCSignup(c *gin.Context) {
+	var request struct {
+		Token       string `json:"token" binding:"required"`
+		DateOfBirth string `json:"date_of_birth" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	signup, err := parseOIDCSignup(request.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Signup session expired, please try again"})
+		return
+	}
+
+	// The same minimum age applies as to signing up with a password
+	fields := validation.FieldErrors{}
+	dateOfBirth, err := time.Parse("2006-01-02", request.DateOfBirth)
+	if err != nil {
+		fields.Add("date_of_birth", "Date of birth must be formatted as YYYY-MM-DD")
+	} else {
+		fields.Add("date_of_birth", validation.DateOfBirth(dateOfBirth, time.Now(), utils.GetEnvInt("MIN_SIGNUP_AGE", validation.DefaultMinAge)))
+	}
+
+	if !fields.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signup data", "fields": fields})
+		return
+	}
+
+	identity := models.Identity{Issuer: signup.IdentityIssuer, Subject: signup.Claims.Subject, Email: signup.Claims.Email, LinkedAt: time.Now()}
+
+	// The token stays valid after the account is created, so it must not create a second one
+	_, err = models.FindUserByIdentity(identity.Issuer, identity.Subject, c.Request.Context())
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "This account already exists, log in instead"})
+		return
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	user, err := provisionUser(c, identity, &signup.Claims, dateOfBirth)
+	if err != nil {
+		return
+	}
+
+	if err := startSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "User created"})
+}
+
+// reauthenticate records that the user who started the reauthentication just logged in again with one of their
+// identities.
+func reauthenticate(c *gin.Context, claims *oidc.Claims, linkedUser *models.User, state oidcState) {
+	if linkedUser == nil || linkedUser.ID.Hex() != state.ReauthUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This login belongs to another account"})
+		return
+	}
+
+	// Providers may ignore the request to log in again, so the login itself has to be recent
+	if claims.AuthTime == nil || time.Since(claims.AuthTime.Time) > oidcStateTTL {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Could not confirm a fresh login, please try again"})
+		return
+	}
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	err := sessionStore.MarkReauthenticated(state.ReauthSessionID, linkedUser.ID, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, utils.GetEnv("APP_URL", "http://localhost:5173")+"/profile?reauthenticated=true")
+}
+
+// provisionUser creates a new user for an identity that is not linked to anyone yet. It writes the error response itself.
+func provisionUser(c *gin.Context, identity models.Identity, claims *oidc.Claims, dateOfBirth time.Time) (*models.User, error) {
+	if claims.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The login provider did not share an email address"})
+		return nil, errors.New("oidc: no email claim")
+	}
+
+	// Linking by email would let anyone who controls the address at the provider take over the account
+	existing, err := models.FindUserByEmail(claims.Email, c.Request.Context())
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists, log in and link it from your profile"})
+		return nil, errors.New("oidc: email already registered")
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return nil, err
+	}
+
+	username, err := availableUsername(c, claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return nil, err
+	}
+
+	// Users created through a provider have no password until they reset it
+	profile := models.NewProfile(claims.GivenName, claims.FamilyName, dateOfBirth, models.NewPreferences())
+	user := models.NewUser(username, claims.Email, "", profile)
+	user.EmailVerified = claims.EmailVerified
+	user.Identities = []models.Identity{identity}
+
+	err = user.Save(c.Request.Context())
+	if models.IsDuplicateIdentity(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This account already exists, log in instead"})
+		return nil, err
+	}
+	if models.DuplicateUserField(err) == "email" {
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists, log in and link it from your profile"})
+		return nil, err
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		log.Println(err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// availableUsername derives a username from the provider's claims, adding digits until it is not taken.
+func availableUsername(c *gin.Context, claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	// Leave room for the digits added when the name is taken
+	base = strings.TrimLeft(usernameReplacer.ReplaceAllString(base, ""), "_.")
+	if len(base) > validation.MaxUsernameLength-4 {
+		base = base[:validation.MaxUsernameLength-4]
+	}
+	if validation.Username(base) != "" {
+		base = "user"
+	}
+
+	username := base
+	for attempt := 0; attempt < 10; attempt++ {
+		_, err := models.FindUserByUsername(username, c.Request.Context())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return username, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		username = base + suffix.String()
+	}
+
+	return "", errors.New("oidc: could not find an available username")
+}
+
+// GetIdentities lists the OpenID Connect identities linked to the authenticated user.
+func GetIdentities(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	identities := user.Identities
+	if identities == nil {
+		identities = []models.Identity{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// UnlinkIdentity removes the identity of the issuer given as a query parameter from the authenticated user.
+func UnlinkIdentity(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	issuer := c.Query("issuer")
+
+	linked := false
+	for _, identity := range user.Identities {
+		if identity.Issuer == issuer {
+			linked = true
+		}
+	}
+
+	if !linked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	}
+
+	// Keep at least one way to log in
+	if user.Password == "" && len(user.Identities) == 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set a password before unlinking your only login method"})
+		return
+	}
+
+	if err := models.UnlinkIdentity(user.ID, issuer, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not unlink account"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Account unlinked"})
+}
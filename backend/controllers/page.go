@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"errors"
+	"infy/models"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageParams are the query parameters of the paginated list endpoints
+type pageParams struct {
+	Limit  int64  `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"` // The next_cursor of the previous page
+}
+
+// bindPage reads the page to return from the query parameters. It responds with an error and returns false if
+// they are invalid.
+func bindPage(c *gin.Context) (models.Page, bool) {
+	var params pageParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters", "details": err.Error()})
+		return models.Page{}, false
+	}
+
+	page, err := models.NewPage(params.Limit, params.Cursor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return models.Page{}, false
+	}
+
+	return page, true
+}
+
+// pageResponse is the response of a paginated list endpoint.
+func pageResponse(key string, items interface{}, next string) gin.H {
+	return gin.H{key: items, "next_cursor": nextCursor(next)}
+}
+
+// nextCursor returns the cursor of the next page for a response, which is null on the last page.
+func nextCursor(next string) interface{} {
+	if next == "" {
+		return nil
+	}
+
+	return next
+}
+
+// respondWithListError responds to an error finding a page of a list, which is the client's fault if the cursor
+// came from a list ordered differently.
+func respondWithListError(c *gin.Context, err error, message string) {
+	if errors.Is(err, models.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	log.Println(err)
+}
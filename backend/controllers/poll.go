@@ -1,60 +1,72 @@
 package controllers
 
 import (
+	"errors"
 	"infy/models"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// GetPollsByMovieID retrieves polls related to a specific movie identified by movieID.
-func GetPollsByMovieID(c *gin.Context) {
-	movieID := c.Param("movieID") // Extracting movieID from the URL parameter
+// GetPolls retrieves a page of the polls related to a specific movie or TV show identified by the URL parameter.
+func GetPolls(mediaType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movieID := mediaIDParam(c, mediaType) // Extracting the movie or TV show ID from the URL parameter
 
-	polls, err := models.FindPollsByMovieID(movieID, c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve polls for the movie"})
-		return
-	}
+		page, ok := bindPage(c)
+		if !ok {
+			return
+		}
 
-	c.JSON(http.StatusOK, polls)
-}
+		polls, next, err := models.FindPollsByMedia(mediaType, movieID, c.Request.Context(), page)
+		if err != nil {
+			respondWithListError(c, err, "Failed to retrieve polls for the "+mediaType)
+			return
+		}
 
-// CreatePoll processes the incoming request to create a new poll associated with a movie.
-func CreatePoll(c *gin.Context) {
-	var newPoll struct {
-		Question string   `json:"question"`
-		Options  []string `json:"options"`
+		c.JSON(http.StatusOK, pageResponse("polls", polls, next))
 	}
+}
 
-	// Bind JSON payload to struct and handle errors
-	if err := c.ShouldBindJSON(&newPoll); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
-	}
+// CreatePoll processes the incoming request to create a new poll associated with a movie or TV show.
+func CreatePoll(mediaType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var newPoll struct {
+			Question string   `json:"question"`
+			Options  []string `json:"options"`
+		}
 
-	movieID := c.Param("movieID") // Extracting movieID from the URL parameter
+		// Bind JSON payload to struct and handle errors
+		if err := c.ShouldBindJSON(&newPoll); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
 
-	// Create a new poll instance
-	poll := models.NewPoll(newPoll.Question, movieID)
+		movieID := mediaIDParam(c, mediaType) // Extracting the movie or TV show ID from the URL parameter
 
-	// Add options to the poll, ensuring no empty options
-	for _, option := range newPoll.Options {
-		if option == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Option text cannot be empty"})
+		// Create a new poll instance
+		poll := models.NewPoll(newPoll.Question, mediaType, movieID)
+
+		// Add options to the poll, ensuring no empty options
+		for _, option := range newPoll.Options {
+			if option == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Option text cannot be empty"})
+				return
+			}
+			poll.AddOption(option)
+		}
+
+		// Save the new poll and handle any errors
+		err := poll.Save(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the poll"})
 			return
 		}
-		poll.AddOption(option)
-	}
 
-	// Save the new poll and handle any errors
-	err := poll.Save(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the poll"})
-		return
+		c.JSON(http.StatusOK, poll)
 	}
-
-	c.JSON(http.StatusOK, poll)
 }
 
 // AddPollVote increments the vote count for a specific option in a poll.
@@ -72,10 +84,20 @@ func AddPollVote(c *gin.Context) {
 
 	// Increment the vote count for the specified poll option
 	err := models.IncrementPollOptionVote(pollID, voteData.OptionID, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Poll option not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add vote to the poll option"})
 		return
 	}
 
+	// Remember the vote for the user's data export, the count above already includes it
+	user := c.MustGet("user").(*models.User)
+	if err := models.RecordPollVote(pollID, voteData.OptionID, user.ID, c.Request.Context()); err != nil {
+		log.Println(err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Vote added successfully"})
 }
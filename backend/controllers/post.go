@@ -1,216 +1,197 @@
 package controllers
 
 import (
-	"infy/api"
+	"errors"
 	"infy/db"
-	"infy/middleware"
 	"infy/models"
+	"infy/tmdb"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// GetPosts retrieves a list of posts and enriches them with user-specific reaction data.
+// GetPosts retrieves a page of posts and enriches them with user-specific reaction data.
 func GetPosts(c *gin.Context) {
 	postCollection := models.PostStore{Collection: db.PostsCollection()}
 
-	// Retrieve posts with a default limit for pagination
-	posts, err := postCollection.FindAllPosts(c.Request.Context(), 20)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts"})
-		log.Println(err)
+	page, ok := bindPage(c)
+	if !ok {
 		return
 	}
 
-	// Attempt to extract the JWT token from cookies
-	token, err := c.Cookie("token")
+	posts, next, err := postCollection.FindAllPosts(viewerID(c), c.Request.Context(), page)
 	if err != nil {
-		token = ""
-	}
-
-	var userID primitive.ObjectID
-	// Decode token to fetch user ID if token is present
-	if token != "" {
-		user, err := middleware.GetUserFromToken(token, c)
-		if err != nil {
-			if err.Error() == "token deleted due to expiration" {
-				return
-			}
-			c.JSON(500, gin.H{"error": "An error occurred"})
-			log.Println(err)
-			return
-		}
-		userID = user.ID
+		respondWithListError(c, err, "Failed to retrieve posts")
+		return
 	}
 
-	// Prepare posts for JSON response
-	var postsResponse []map[string]interface{}
-	for _, post := range posts {
-		var likes, dislikes int
-		var liked, disliked bool
-
-		// Count likes and dislikes for each post
-		for _, reaction := range post.Reactions {
-			if reaction.Liked {
-				likes++
-			}
-			if reaction.Disliked {
-				dislikes++
-			}
-			// Check if the current user has liked or disliked the post
-			if reaction.UserID == userID {
-				liked = reaction.Liked
-				disliked = reaction.Disliked
-			}
-		}
-
-		// Append the post to the response
-		postsResponse = append(postsResponse, map[string]interface{}{
-			"post":     post,
-			"liked":    liked,
-			"disliked": disliked,
-			"likes":    likes,
-			"dislikes": dislikes,
-			"created":  post.ID.Timestamp().Format("2006-01-02 15:04:05"),
-		})
+	authors := models.AuthorLoader{Collection: db.UsersCollection()}
+	if err := authors.LoadPostAuthors(posts, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts"})
+		log.Println(err)
+		return
 	}
 
-	c.JSON(200, postsResponse)
+	c.JSON(200, pageResponse("posts", postViews(posts), next))
 }
 
-// GetPost retrieves a single post by ID and its associated comments, including user-specific reaction data.
+// GetPost retrieves a single post by ID and the first page of its top-level comments with their first replies,
+// including user-specific reaction data.
 func GetPost(c *gin.Context) {
 	postCollection := models.PostStore{Collection: db.PostsCollection()}
+	viewer := viewerID(c)
 
 	// Get the post by ID
-	limit := int64(20)
-	post, err := postCollection.FindPostByID(c.Param("id"), c.Request.Context())
+	post, err := postCollection.FindPostByID(c.Param("id"), viewer, c.Request.Context())
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		if errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+			return
+		}
 		c.JSON(500, gin.H{"error": "An error occurred"})
 		log.Println(err)
 		return
 	}
-	// Get all comments for the post since it's the post details
-	comments, err := models.FindCommentsByPostID(post.ID.Hex(), c.Request.Context(), limit)
+	// Get the first page of comments, the rest can be loaded from /posts/:id/comments with comments_next_cursor
+	comments, commentsNext, err := models.FindCommentsByPostID(post.ID, viewer, c.Request.Context(), models.Page{})
 	if err != nil {
 		c.JSON(500, gin.H{"error": "An error occurred"})
 		return
 	}
 
-	// Get the token from the cookie
-	token, err := c.Cookie("token")
-	if err != nil {
-		token = ""
-	}
-
-	// Get the user ID from the token
-	var userID primitive.ObjectID
-	if token != "" {
-		user, err := middleware.GetUserFromToken(token, c)
-		if err != nil {
-			if err.Error() == "token deleted due to expiration" {
-				return
-			}
-			c.JSON(500, gin.H{"error": "An error occurred"})
-			log.Println(err)
-			return
-		}
-
-		userID = user.ID
+	// One loader looks up the authors of the post and its comments together
+	authors := models.AuthorLoader{Collection: db.UsersCollection()}
+	if err := authors.LoadPostAuthors([]*models.Post{post}, c.Request.Context()); err != nil {
+		c.JSON(500, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
 	}
-
-	// Like and dislike counters
-	var likes, dislikes int = 0, 0
-	// Like and dislike status
-	var liked, disliked bool = false, false
-
-	// Check if the user has liked the post and increment the like or dislike counters
-	for _, reaction := range post.Reactions {
-		if reaction.Liked {
-			likes++
-		}
-
-		if reaction.Disliked {
-			dislikes++
-		}
-
-		if reaction.UserID == userID {
-			liked = reaction.Liked
-			disliked = reaction.Disliked
-		}
+	if err := loadCommentThreads(c, comments, viewer, &authors); err != nil {
+		c.JSON(500, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
 	}
 
-	postResponse := map[string]interface{}{
-		"post":     post,
-		"liked":    liked,
-		"disliked": disliked,
-		"likes":    likes,
-		"dislikes": dislikes,
-		"created":  post.ID.Timestamp().Format("2006-01-02 15:04:05"),
-		"comments": comments,
-	}
+	postResponse := postView(post)
+	postResponse["comments"] = comments
+	postResponse["comments_next_cursor"] = nextCursor(commentsNext)
 
 	c.JSON(200, postResponse)
 }
 
-// GetPostsByMovieID fetches all posts related to a specific movie by the movie's ID.
-func GetPostsByMovieID(c *gin.Context) {
-	postCollection := models.PostStore{Collection: db.PostsCollection()}
-	movieID := c.Param("movieID") // Extracting movieID from the URL parameter
-	limit := int64(20)
-
-	posts, err := postCollection.FindPostsByMovieID(movieID, c.Request.Context(), limit)
+// GetPostComments retrieves a page of the top-level comments on a post with their first replies.
+func GetPostComments(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts for the movie"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, posts)
-}
-
-// CreatePost handles the creation of a new post related to a movie.
-func CreatePost(c *gin.Context) {
-	postCollection := models.PostStore{Collection: db.PostsCollection()}
-
-	var post struct {
-		MovieID string `json:"movie_id" binding:"required"`
-		Content string `json:"content" binding:"required"`
-	}
-
-	// Bind the request body to the post struct
-	if err := c.ShouldBindJSON(&post); err != nil {
-		c.JSON(400, gin.H{"error": "An error occurred"})
-		log.Println(err)
+	page, ok := bindPage(c)
+	if !ok {
 		return
 	}
 
-	// Get the user from the context
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(500, gin.H{"error": "An error occurred"})
+	viewer := viewerID(c)
+	comments, next, err := models.FindCommentsByPostID(postID, viewer, c.Request.Context(), page)
+	if err != nil {
+		respondWithListError(c, err, "Failed to retrieve comments")
 		return
 	}
 
-	// Get the movie details
-	_, movie, err := api.GetMovieDetails(post.MovieID, true)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "An error occurred"})
+	authors := models.AuthorLoader{Collection: db.UsersCollection()}
+	if err := loadCommentThreads(c, comments, viewer, &authors); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
 		log.Println(err)
 		return
 	}
 
-	// Create the post
-	newPost := models.NewPost(user.(*models.User), movie, post.Content)
-	if err := postCollection.Save(c.Request.Context(), newPost); err != nil {
-		c.JSON(500, gin.H{"error": "An error occurred"})
-		log.Println(err)
-		return
+	c.JSON(http.StatusOK, pageResponse("comments", comments, next))
+}
+
+// GetPostsByMedia fetches all posts related to a specific movie or TV show by its ID.
+func GetPostsByMedia(mediaType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		postCollection := models.PostStore{Collection: db.PostsCollection()}
+
+		mediaID, err := strconv.Atoi(mediaIDParam(c, mediaType)) // Extracting the movie or TV show ID from the URL parameter
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + mediaType + " ID"})
+			return
+		}
+
+		page, ok := bindPage(c)
+		if !ok {
+			return
+		}
+
+		posts, next, err := postCollection.FindPostsByMedia(mediaType, mediaID, viewerID(c), c.Request.Context(), page)
+		if err != nil {
+			respondWithListError(c, err, "Failed to retrieve posts for the "+mediaType)
+			return
+		}
+
+		authors := models.AuthorLoader{Collection: db.UsersCollection()}
+		if err := authors.LoadPostAuthors(posts, c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts for the " + mediaType})
+			log.Println(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, pageResponse("posts", postViews(posts), next))
 	}
+}
+
+// CreatePost handles the creation of a new post related to a movie or TV show.
+func CreatePost(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		postCollection := models.PostStore{Collection: db.PostsCollection()}
+
+		var post struct {
+			MovieID   string `json:"movie_id" binding:"required"`
+			MediaType string `json:"media_type" binding:"omitempty,oneof=movie tv"` // Defaults to movie
+			Content   string `json:"content" binding:"required"`
+		}
+
+		// Bind the request body to the post struct
+		if err := c.ShouldBindJSON(&post); err != nil {
+			c.JSON(400, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+
+		// Get the user from the context
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(500, gin.H{"error": "An error occurred"})
+			return
+		}
+
+		// Get the movie or TV show details
+		movie, err := client.GetMedia(c.Request.Context(), post.MediaType, post.MovieID)
+		if err != nil {
+			respondWithTMDBError(c, err, "An error occurred")
+			return
+		}
+
+		// Create the post
+		newPost := models.NewPost(user.(*models.User), movie, post.Content)
+		if err := postCollection.Save(c.Request.Context(), newPost); err != nil {
+			c.JSON(500, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
 
-	c.JSON(200, newPost)
+		c.JSON(200, newPost)
+	}
 }
 
 // UpdatePost allows authorized users to modify an existing post.
@@ -292,135 +273,50 @@ func DeletePost(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Post deleted successfully"})
 }
 
-// LikePost handles the action of a user liking a post.
-func LikePost(c *gin.Context) {
-	postCollection := models.PostStore{Collection: db.PostsCollection()}
-
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(500, gin.H{"error": "An error occurred"})
-		log.Println("User not found in context")
-		return
-	}
-
-	var reaction struct {
-		IsLiked bool `json:"is_liked"`
-	}
-
-	if err := c.ShouldBindJSON(&reaction); err != nil {
-		c.JSON(400, gin.H{"error": "An error occurred"})
-		log.Println(err)
-		return
-	}
-
-	err := postCollection.UpdateReaction(c.Param("id"), user.(*models.User).ID, !reaction.IsLiked, false, c.Request.Context())
-	if err != nil {
-		c.JSON(500, gin.H{"error": "An error occurred"})
-		log.Println(err)
-		return
+// LikePost likes a post for the authenticated user, or takes the like back if they already liked it. Whether the
+// post is liked is decided by the stored reactions, so a stale client cannot remove another reaction of the user.
+func LikePost(reactions *models.ReactionSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setReaction(c, postReactions(), reactions, models.ReactionLike, "post", true)
 	}
 }
 
-// DislikePost handles the action of a user disliking a post.
-func DislikePost(c *gin.Context) {
-	postCollection := models.PostStore{Collection: db.PostsCollection()}
-
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(500, gin.H{"error": "An error occurred"})
-		log.Println("User not found in context")
-		return
-	}
-
-	var reaction struct {
-		IsDisliked bool `json:"is_disliked"`
-	}
-
-	if err := c.ShouldBindJSON(&reaction); err != nil {
-		c.JSON(400, gin.H{"error": "An error occurred"})
-		log.Println(err)
-		return
-	}
-
-	err := postCollection.UpdateReaction(c.Param("id"), user.(*models.User).ID, false, !reaction.IsDisliked, c.Request.Context())
-	if err != nil {
-		c.JSON(500, gin.H{"error": "An error occurred"})
-		log.Println(err)
-		return
+// DislikePost dislikes a post for the authenticated user, or takes the dislike back if they already disliked it.
+func DislikePost(reactions *models.ReactionSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setReaction(c, postReactions(), reactions, models.ReactionDislike, "post", true)
 	}
 }
 
-// GetUserPosts retrieves posts created by a specific user.
+// GetUserPosts retrieves a page of the posts created by a specific user.
 func GetUserPosts(c *gin.Context) {
 	postCollection := models.PostStore{Collection: db.PostsCollection()}
 
 	userID := c.Param("userID")
-	limit := int64(20)
 
-	posts, err := postCollection.FindPostsByUserID(userID, c.Request.Context(), limit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user's posts"})
+	page, ok := bindPage(c)
+	if !ok {
 		return
 	}
 
-	// Get the token from the cookie
-	token, err := c.Cookie("token")
+	posts, next, err := postCollection.FindPostsByUserID(userID, viewerID(c), c.Request.Context(), page)
 	if err != nil {
-		token = ""
-	}
-
-	// Get the user ID from the token
-	var authenticatedUserID primitive.ObjectID
-	if token != "" {
-		user, err := middleware.GetUserFromToken(token, c)
-		if err != nil {
-			if err.Error() == "token deleted due to expiration" {
-				return
-			}
-			c.JSON(500, gin.H{"error": "An error occurred"})
-			log.Println(err)
+		if errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 			return
 		}
-
-		authenticatedUserID = user.ID
+		respondWithListError(c, err, "Failed to retrieve user's posts")
+		return
 	}
 
-	// Create a response with the post and the created date
-	var postsResponse []map[string]interface{}
-	for _, post := range posts {
-		// Like and dislike counters
-		var likes, dislikes int = 0, 0
-		// Like and dislike status
-		var liked, disliked bool = false, false
-
-		// Check if the user has liked the post and increment the like or dislike counters
-		for _, reaction := range post.Reactions {
-			if reaction.Liked {
-				likes++
-			}
-
-			if reaction.Disliked {
-				dislikes++
-			}
-
-			if reaction.UserID == authenticatedUserID {
-				liked = reaction.Liked
-				disliked = reaction.Disliked
-			}
-		}
-
-		// Append the post to the response
-		postsResponse = append(postsResponse, map[string]interface{}{
-			"post":     post,
-			"liked":    liked,
-			"disliked": disliked,
-			"likes":    likes,
-			"dislikes": dislikes,
-			"created":  post.ID.Timestamp().Format("2006-01-02 15:04:05"),
-		})
+	authors := models.AuthorLoader{Collection: db.UsersCollection()}
+	if err := authors.LoadPostAuthors(posts, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user's posts"})
+		log.Println(err)
+		return
 	}
 
-	c.JSON(http.StatusOK, postsResponse)
+	c.JSON(http.StatusOK, pageResponse("posts", postViews(posts), next))
 }
 
 // ReportPost allows a user to report a post as inappropriate.
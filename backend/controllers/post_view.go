@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"infy/middleware"
+	"infy/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// viewerID returns the ID of the user reading posts, or the nil ID for visitors who are not logged in.
+// The cookie or Authorization header is optional on the routes that show posts.
+func viewerID(c *gin.Context) primitive.ObjectID {
+	if user := middleware.OptionalUser(c); user != nil {
+		return user.ID
+	}
+
+	return primitive.NilObjectID
+}
+
+// postView builds the response for a post with its reaction counts and the viewer's reaction, which is the same
+// wherever posts are shown. The post has to be read with the viewer's reaction. Liked, disliked, likes and dislikes
+// are kept for clients that only know about likes and dislikes.
+func postView(post *models.Post) map[string]interface{} {
+	var reaction interface{}
+	if post.ViewerReaction != "" {
+		reaction = post.ViewerReaction
+	}
+
+	return map[string]interface{}{
+		"post":     post,
+		"reaction": reaction,
+		"liked":    post.ViewerReaction == models.ReactionLike,
+		"disliked": post.ViewerReaction == models.ReactionDislike,
+		"likes":    post.ReactionCounts[models.ReactionLike],
+		"dislikes": post.ReactionCounts[models.ReactionDislike],
+		"created":  post.ID.Timestamp().Format("2006-01-02 15:04:05"),
+	}
+}
+
+// postViews builds the responses for a list of posts.
+func postViews(posts []*models.Post) []map[string]interface{} {
+	views := make([]map[string]interface{}, 0, len(posts))
+	for _, post := range posts {
+		views = append(views, postView(post))
+	}
+
+	return views
+}
@@ -1,12 +1,19 @@
 package controllers
 
 import (
-	"infy/api"
+	"errors"
 	"infy/models"
+	"infy/tmdb"
+	"infy/validation"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // GetUserProfile retrieves and returns the profile of the currently authenticated user.
@@ -20,19 +27,87 @@ func GetUserProfile(c *gin.Context) {
 	c.JSON(200, gin.H{"profile": user.Profile}) // Successfully returns the user's profile.
 }
 
+// UpdateUserProfile updates the name and favorite genres of the authenticated user. Only the fields present in the
+// request are changed.
+func UpdateUserProfile(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var request struct {
+		FirstName *string   `json:"first_name"`
+		LastName  *string   `json:"last_name"`
+		Genres    *[]string `json:"genres"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	fields := validation.FieldErrors{}
+	update := bson.M{}
+
+	if request.FirstName != nil {
+		*request.FirstName = strings.TrimSpace(*request.FirstName)
+		fields.Required("first_name", *request.FirstName)
+		fields.Add("first_name", validation.Name(*request.FirstName))
+		update["profile.first_name"] = *request.FirstName
+	}
+
+	if request.LastName != nil {
+		*request.LastName = strings.TrimSpace(*request.LastName)
+		fields.Required("last_name", *request.LastName)
+		fields.Add("last_name", validation.Name(*request.LastName))
+		update["profile.last_name"] = *request.LastName
+	}
+
+	if request.Genres != nil {
+		fields.Add("genres", validation.Genres(*request.Genres))
+		update["profile.preferences.genres"] = *request.Genres
+	}
+
+	if !fields.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "fields": fields})
+		return
+	}
+
+	if len(update) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	updated, err := models.UpdateProfile(user.ID, update, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update profile"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"profile": updated.Profile})
+}
+
 // GetProfile fetches and returns a user profile based on a username provided as a URL parameter.
 func GetProfile(c *gin.Context) {
 	user, err := models.FindUserByUsername(c.Param("username"), c.Request.Context())
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(404, gin.H{"error": "User not found"})
+			return
+		}
+
 		c.JSON(500, gin.H{"error": "An error occurred"}) // Error handling if the profile cannot be retrieved.
 		log.Println(err)
 		return
 	}
 
+	// Accounts being deleted are hidden during the grace period
+	if user.DeleteAfter != nil {
+		c.JSON(404, gin.H{"error": "User not found"})
+		return
+	}
+
 	profileResponse := map[string]interface{}{
 		"id":       user.ID.Hex(),
 		"username": user.Username,
-		"is_admin": user.IsAdmin,
+		"role":     user.Role,
 		"profile":  user.Profile,
 	}
 
@@ -76,86 +151,212 @@ func Unfollow(c *gin.Context) {
 }
 
 // AddMovieToWatched adds a specified movie to the authenticated user's watched list.
-func AddMovieToWatched(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"}) // Checks if the user is authenticated.
-		return
+func AddMovieToWatched(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"}) // Checks if the user is authenticated.
+			return
+		}
+
+		userID := user.(*models.User).ID.Hex() // Extracts userID from the user context.
+
+		var requestBody struct {
+			MovieID   string `json:"movieId"`
+			MediaType string `json:"media_type" binding:"omitempty,oneof=movie tv"` // Defaults to movie
+		}
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()}) // Validates the JSON body.
+			log.Println(err)
+			return
+		}
+
+		isValid, err := client.IsValidMediaID(c.Request.Context(), requestBody.MediaType, requestBody.MovieID) // Validates the movie or TV show ID against an external API.
+		if err != nil {
+			respondWithTMDBError(c, err, "Error validating movie ID") // Handles API errors.
+			return
+		}
+
+		if !isValid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"}) // Handles invalid movie ID.
+			log.Println(err)
+			return
+		}
+
+		err = models.AddMovieToWatchedList(userID, models.MediaKey(requestBody.MediaType, requestBody.MovieID), c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add movie to watched list"}) // Handles failure in adding to watched list.
+			log.Println(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Movie added to watched list"}) // Success response.
 	}
+}
 
-	userID := user.(*models.User).ID.Hex() // Extracts userID from the user context.
-
-	var requestBody struct {
-		MovieID string `json:"movieId"`
-	}
-	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()}) // Validates the JSON body.
-		log.Println(err)
-		return
+// AddMovieToWatchlist adds a specified movie to the authenticated user's watchlist.
+func AddMovieToWatchlist(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"}) // Checks if the user is authenticated.
+			return
+		}
+
+		userID := user.(*models.User).ID.Hex() // Extracts userID from the user context.
+
+		var requestBody struct {
+			MovieID   string `json:"movieId"`
+			MediaType string `json:"media_type" binding:"omitempty,oneof=movie tv"` // Defaults to movie
+		}
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"}) // Validates the JSON body.
+			log.Println(err)
+			return
+		}
+
+		isValid, err := client.IsValidMediaID(c.Request.Context(), requestBody.MediaType, requestBody.MovieID) // Validates the movie or TV show ID against an external API.
+		if err != nil {
+			respondWithTMDBError(c, err, "Error validating movie ID") // Handles API errors.
+			return
+		}
+
+		if !isValid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"}) // Handles invalid movie ID.
+			log.Println(err)
+			return
+		}
+
+		err = models.AddMovieToWatchlist(userID, models.MediaKey(requestBody.MediaType, requestBody.MovieID), c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add movie to watchlist"}) // Handles failure in adding to watchlist.
+			log.Println(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Movie added to watchlist"}) // Success response.
 	}
+}
 
-	isValid, err := api.IsValidMovieID(requestBody.MovieID) // Validates the movie ID against an external API.
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error validating movie ID"}) // Handles API errors.
-		log.Println(err)
-		return
+// watchListLookupLimit bounds the concurrent TMDB lookups made for a watch list
+const watchListLookupLimit = 5
+
+// lookUpConcurrently calls lookup for the indexes 0 to n-1, at most watchListLookupLimit at a time, and returns when
+// all lookups are done.
+func lookUpConcurrently(n int, lookup func(i int)) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, watchListLookupLimit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			lookup(i)
+		}(i)
 	}
+	wg.Wait()
+}
 
-	if !isValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"}) // Handles invalid movie ID.
-		log.Println(err)
-		return
-	}
+// watchlistMovie is a watchlist movie flagged with the user's services it can currently be streamed on.
+type watchlistMovie struct {
+	*models.MovieRecord
+	Streamable           bool            `json:"streamable"`
+	StreamingOn          []tmdb.Provider `json:"streaming_on"`
+	ProvidersUnavailable bool            `json:"providers_unavailable,omitempty"` // The streaming services could not be looked up
+}
 
-	err = models.AddMovieToWatchedList(userID, requestBody.MovieID, c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add movie to watched list"}) // Handles failure in adding to watched list.
-		log.Println(err)
-		return
+// GetWatchlist returns the movies and TV shows on the authenticated user's watchlist,
+// flagging the movies that are streamable on the services in the user's region.
+// Movies whose streaming services cannot be looked up are marked instead of failing the whole watchlist.
+func GetWatchlist(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
+		preferences := user.Profile.Preferences
+
+		movies, shows, err := loadWatchList(c, client, preferences.WatchList)
+		if err != nil {
+			respondWithTMDBError(c, err, "Could not retrieve watchlist")
+			return
+		}
+
+		flagged := make([]watchlistMovie, len(movies))
+		for i, movie := range movies {
+			flagged[i] = watchlistMovie{MovieRecord: movie, StreamingOn: []tmdb.Provider{}}
+		}
+
+		// Without any services there is nothing to match the providers against
+		if len(preferences.Services) > 0 {
+			lookUpConcurrently(len(flagged), func(i int) {
+				item := &flagged[i]
+				providers, err := client.GetMovieWatchProviders(c.Request.Context(), strconv.Itoa(item.ID), preferences.Region)
+				if err != nil {
+					item.ProvidersUnavailable = true
+					log.Println(err)
+					return
+				}
+
+				item.StreamingOn = providers.StreamingOn(preferences.Services)
+				item.Streamable = len(item.StreamingOn) > 0
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"movies": flagged, "tv": shows})
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Movie added to watched list"}) // Success response.
 }
 
-// AddMovieToWatchlist adds a specified movie to the authenticated user's watchlist.
-func AddMovieToWatchlist(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"}) // Checks if the user is authenticated.
-		return
-	}
+// GetWatched returns the movies and TV shows on the authenticated user's watched list.
+func GetWatched(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
 
-	userID := user.(*models.User).ID.Hex() // Extracts userID from the user context.
+		movies, shows, err := loadWatchList(c, client, user.Profile.Preferences.Watched)
+		if err != nil {
+			respondWithTMDBError(c, err, "Could not retrieve watched list")
+			return
+		}
 
-	var requestBody struct {
-		MovieID string `json:"movieId"`
-	}
-	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"}) // Validates the JSON body.
-		log.Println(err)
-		return
+		c.JSON(http.StatusOK, gin.H{"movies": movies, "tv": shows})
 	}
+}
 
-	isValid, err := api.IsValidMovieID(requestBody.MovieID) // Validates the movie ID against an external API.
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error validating movie ID"}) // Handles API errors.
-		log.Println(err)
-		return
+// loadWatchList splits the watch list keys by media type, reading movies from the local catalog and TV shows from TMDB.
+// TV shows are looked up concurrently, and shows that cannot be looked up are left out.
+func loadWatchList(c *gin.Context, client *tmdb.Client, keys []string) ([]*models.MovieRecord, []*tmdb.TVDetails, error) {
+	var movieIDs, tvIDs []string
+	for _, key := range keys {
+		mediaType, id := models.ParseMediaKey(key)
+		if mediaType == models.MediaTypeTV {
+			tvIDs = append(tvIDs, id)
+		} else {
+			movieIDs = append(movieIDs, id)
+		}
 	}
 
-	if !isValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"}) // Handles invalid movie ID.
-		log.Println(err)
-		return
+	movies, err := client.CatalogMovies(c.Request.Context(), movieIDs)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	err = models.AddMovieToWatchlist(userID, requestBody.MovieID, c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add movie to watchlist"}) // Handles failure in adding to watchlist.
-		log.Println(err)
-		return
+	found := make([]*tmdb.TVDetails, len(tvIDs))
+	lookUpConcurrently(len(tvIDs), func(i int) {
+		show, err := client.GetTVDetails(c.Request.Context(), tvIDs[i])
+		if err != nil {
+			if !errors.Is(err, tmdb.ErrNotFound) {
+				log.Println(err)
+			}
+			return
+		}
+		found[i] = show
+	})
+
+	shows := make([]*tmdb.TVDetails, 0, len(tvIDs))
+	for _, show := range found {
+		if show != nil {
+			shows = append(shows, show)
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Movie added to watchlist"}) // Success response.
+	return movies, shows, nil
 }
 
 // RemoveMovieFromWatched removes a specified movie from the authenticated user's watched list.
@@ -169,7 +370,8 @@ func RemoveMovieFromWatched(c *gin.Context) {
 	userID := user.(*models.User).ID.Hex() // Converts ObjectID to string.
 	movieID := c.Param("id")               // Assumes the movie ID is passed as a URL parameter.
 
-	err := models.RemoveMovieFromWatchedList(userID, movieID, c.Request.Context())
+	// TV shows are removed with ?media_type=tv
+	err := models.RemoveMovieFromWatchedList(userID, models.MediaKey(c.Query("media_type"), movieID), c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove movie from watched list"}) // Handles failure in removing from watched list.
 		log.Println(err)
@@ -189,7 +391,8 @@ func RemoveMovieFromWatchlist(c *gin.Context) {
 	userID := user.(*models.User).ID.Hex() // Converts ObjectID to string.
 	movieID := c.Param("id")               // Gets the movie ID from the URL parameter.
 
-	err := models.RemoveMovieFromWatchlist(userID, movieID, c.Request.Context())
+	// TV shows are removed with ?media_type=tv
+	err := models.RemoveMovieFromWatchlist(userID, models.MediaKey(c.Query("media_type"), movieID), c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove movie from watchlist"}) // Handles failure in removing from watchlist.
 		log.Println(err)
@@ -199,6 +402,34 @@ func RemoveMovieFromWatchlist(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Movie removed from watchlist successfully"}) // Success response.
 }
 
+// UpdateStreamingSettings sets the region and streaming services used to flag streamable watchlist movies.
+func UpdateStreamingSettings(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var requestBody struct {
+		Region   string `json:"region" binding:"required,iso3166_1_alpha2"`
+		Services []int  `json:"services" binding:"dive,min=1"` // TMDB watch provider IDs
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		log.Println(err)
+		return
+	}
+
+	if requestBody.Services == nil {
+		requestBody.Services = []int{}
+	}
+
+	err := models.UpdateStreamingSettings(user.ID.Hex(), requestBody.Region, requestBody.Services, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update streaming settings"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Streaming settings updated"})
+}
+
 // AddUserAvatar uploads and sets a user avatar, validating the image type and size.
 func AddUserAvatar(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"errors"
+	"infy/db"
+	"infy/models"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// postReactions returns the store of the reactions to posts.
+func postReactions() models.ReactionStore {
+	return models.ReactionStore{Collection: db.PostsCollection()}
+}
+
+// commentReactions returns the store of the reactions to comments.
+func commentReactions() models.ReactionStore {
+	return models.ReactionStore{Collection: db.CommentsCollection()}
+}
+
+// setReaction sets the authenticated user's reaction to the post or comment in the id parameter, or removes it if
+// the reaction type is empty, and responds with the user's reaction and the new reaction counts. With toggle, a
+// reaction the user already gave is removed instead. The target is the name of what the reaction is given to, for
+// the error messages.
+func setReaction(c *gin.Context, store models.ReactionStore, reactions *models.ReactionSet, reactionType, target string, toggle bool) {
+	if reactionType != "" && !reactions.Has(reactionType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown reaction type", "reaction_types": reactions.Types()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(500, gin.H{"error": "An error occurred"})
+		log.Println("User not found in context")
+		return
+	}
+
+	update := store.SetReaction
+	if toggle {
+		update = store.ToggleReaction
+	}
+
+	state, err := update(c.Param("id"), user.(*models.User).ID, reactionType, c.Request.Context())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": strings.ToUpper(target[:1]) + target[1:] + " not found"})
+			return
+		}
+		if errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + target + " ID"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	var reaction interface{}
+	if state.Reaction != "" {
+		reaction = state.Reaction
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reaction": reaction, "reaction_counts": state.Counts})
+}
+
+// reactionRequest is the body of the requests that react to a post or comment
+type reactionRequest struct {
+	Type string `json:"type" binding:"required"`
+}
+
+// ReactToPost sets the authenticated user's reaction to a post, replacing their previous reaction.
+func ReactToPost(reactions *models.ReactionSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request reactionRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			log.Println(err)
+			return
+		}
+
+		setReaction(c, postReactions(), reactions, request.Type, "post", false)
+	}
+}
+
+// RemovePostReaction removes the authenticated user's reaction to a post.
+func RemovePostReaction(c *gin.Context) {
+	setReaction(c, postReactions(), nil, "", "post", false)
+}
+
+// GetPostReactions retrieves a page of the reactions to a post with the users who gave them, only of the type in
+// the type query parameter if it is set.
+func GetPostReactions(reactions *models.ReactionSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reactionType := c.Query("type")
+		if reactionType != "" && !reactions.Has(reactionType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown reaction type", "reaction_types": reactions.Types()})
+			return
+		}
+
+		page, ok := bindPage(c)
+		if !ok {
+			return
+		}
+
+		store := postReactions()
+		list, next, err := store.FindReactions(c.Param("id"), reactionType, c.Request.Context(), page)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+				return
+			}
+			respondWithListError(c, err, "Failed to retrieve reactions")
+			return
+		}
+
+		authors := models.AuthorLoader{Collection: db.UsersCollection()}
+		if err := authors.LoadReactionAuthors(list, c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reactions"})
+			log.Println(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, pageResponse("reactions", list, next))
+	}
+}
+
+// ReactToComment sets the authenticated user's reaction to a comment, replacing their previous reaction.
+func ReactToComment(reactions *models.ReactionSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request reactionRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			log.Println(err)
+			return
+		}
+
+		setReaction(c, commentReactions(), reactions, request.Type, "comment", false)
+	}
+}
+
+// RemoveCommentReaction removes the authenticated user's reaction to a comment.
+func RemoveCommentReaction(c *gin.Context) {
+	setReaction(c, commentReactions(), nil, "", "comment", false)
+}
+
+// LikeComment likes a comment for the authenticated user, or takes the like back if they already liked it.
+func LikeComment(reactions *models.ReactionSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setReaction(c, commentReactions(), reactions, models.ReactionLike, "comment", true)
+	}
+}
+
+// DislikeComment dislikes a comment for the authenticated user, or takes the dislike back if they already
+// disliked it.
+func DislikeComment(reactions *models.ReactionSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setReaction(c, commentReactions(), reactions, models.ReactionDislike, "comment", true)
+	}
+}
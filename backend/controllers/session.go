@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"errors"
+	"infy/db"
+	"infy/models"
+	"infy/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	// refreshCookiePath limits the refresh token cookie to the auth routes so it is not sent with every request
+	refreshCookiePath = "/auth"
+)
+
+// startSession creates a new session for the user on the requesting device and sets the access and refresh token cookies.
+func startSession(c *gin.Context, user *models.User) error {
+	// Logging in again during the grace period keeps an account that was being deleted
+	if user.DeleteAfter != nil {
+		if err := models.CancelUserDeletion(user.ID, c.Request.Context()); err != nil {
+			return err
+		}
+		user.DeleteAfter = nil
+	}
+
+	refreshToken, err := utils.NewToken()
+	if err != nil {
+		return err
+	}
+
+	refreshTTL := utils.GetEnvDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+	session := models.NewSession(user.ID, utils.HashToken(refreshToken), c.Request.UserAgent(), c.ClientIP(), refreshTTL)
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	if err := sessionStore.Save(session, c.Request.Context()); err != nil {
+		return err
+	}
+
+	return setAuthCookies(c, user, session, refreshToken)
+}
+
+// setAuthCookies issues a new access token for the session and sets it together with the refresh token as cookies.
+func setAuthCookies(c *gin.Context, user *models.User, session *models.Session, refreshToken string) error {
+	accessTTL := utils.GetEnvDuration("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+	token, err := user.GetJwtToken(session.ID.Hex(), time.Now().Add(accessTTL))
+	if err != nil {
+		return err
+	}
+
+	domain := utils.GetEnv("SITE_DOMAIN", "localhost")
+	c.SetCookie("token", token, int(accessTTL.Seconds()), "/", domain, utils.IsProd(), true)
+	c.SetCookie("refresh_token", refreshToken, int(time.Until(session.ExpiresAt).Seconds()), refreshCookiePath, domain, utils.IsProd(), true)
+
+	return nil
+}
+
+// clearAuthCookies removes the access and refresh token cookies.
+func clearAuthCookies(c *gin.Context) {
+	domain := utils.GetEnv("SITE_DOMAIN", "localhost")
+	c.SetCookie("token", "", -1, "/", domain, utils.IsProd(), true)
+	c.SetCookie("refresh_token", "", -1, refreshCookiePath, domain, utils.IsProd(), true)
+}
+
+// Refresh exchanges the refresh token for a new access token, rotating the refresh token at the same time.
+func Refresh(c *gin.Context) {
+	refreshToken, err := c.Cookie("refresh_token")
+	if err != nil || refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	tokenHash := utils.HashToken(refreshToken)
+
+	session, err := sessionStore.FindSessionByTokenHash(tokenHash, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// A rotated token being used again means it was stolen, so end the session for both parties
+		if reused, err := sessionStore.FindSessionByPreviousTokenHash(tokenHash, c.Request.Context()); err == nil {
+			if err := sessionStore.RevokeSession(reused.ID.Hex(), reused.UserID, c.Request.Context()); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				log.Println(err)
+			}
+		}
+
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	if !session.IsActive() {
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired"})
+		return
+	}
+
+	user, err := models.FindUserByID(session.UserID.Hex(), c.Request.Context())
+	if err != nil {
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		log.Println(err)
+		return
+	}
+
+	newRefreshToken, err := utils.NewToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		log.Println(err)
+		return
+	}
+
+	refreshTTL := utils.GetEnvDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+	err = sessionStore.RotateSession(session, utils.HashToken(newRefreshToken), refreshTTL, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Another request rotated the token or the session was revoked in the meantime
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	if err := setAuthCookies(c, user, session, newRefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Token refreshed"})
+}
+
+// GetSessions lists the active sessions of the authenticated user, marking the session of the current request.
+func GetSessions(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	currentID := c.GetString("session_id")
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	sessions, err := sessionStore.FindActiveSessionsByUserID(user.ID, c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve sessions"})
+		log.Println(err)
+		return
+	}
+
+	response := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, gin.H{
+			"id":           session.ID.Hex(),
+			"device":       session.Device,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID.Hex() == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": response})
+}
+
+// RevokeSession logs the authenticated user out of one of their sessions.
+func RevokeSession(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	if _, err := primitive.ObjectIDFromHex(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	err := sessionStore.RevokeSession(c.Param("id"), user.ID, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke session"})
+		log.Println(err)
+		return
+	}
+
+	if c.Param("id") == c.GetString("session_id") {
+		clearAuthCookies(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Session revoked"})
+}
+
+// RevokeOtherSessions logs the authenticated user out of every session except the current one.
+func RevokeOtherSessions(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	currentID, _ := primitive.ObjectIDFromHex(c.GetString("session_id"))
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	if err := sessionStore.RevokeUserSessions(user.ID, currentID, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke sessions"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Other sessions revoked"})
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"infy/models"
+	"infy/tmdb"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mediaIDParam returns the movie or TV show ID from the URL parameters of the /movies or /tv routes.
+func mediaIDParam(c *gin.Context, mediaType string) string {
+	if mediaType == models.MediaTypeTV {
+		return c.Param("tvID")
+	}
+
+	return c.Param("movieID")
+}
+
+// GetTVDetails fetches details for a single TV show identified by its ID.
+func GetTVDetails(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tvDetails, err := client.GetTVDetails(c.Request.Context(), c.Param("tvID"))
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to get TV show details")
+			return
+		}
+
+		c.JSON(http.StatusOK, tvDetails)
+	}
+}
+
+// GetTVCast retrieves the cast of a specific TV show by its ID.
+func GetTVCast(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cast, err := client.GetTVCast(c.Request.Context(), c.Param("tvID"))
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch TV show cast")
+			return
+		}
+
+		c.JSON(http.StatusOK, cast)
+	}
+}
+
+// GetSimilarTV retrieves TV shows similar to a specified show by its ID.
+func GetSimilarTV(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		similar, err := client.GetSimilarTV(c.Request.Context(), c.Param("tvID"))
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch similar TV shows")
+			return
+		}
+
+		c.JSON(http.StatusOK, similar)
+	}
+}
+
+// GetTVTrailers retrieves the trailers and other videos of a specific TV show by its ID.
+func GetTVTrailers(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trailers, err := client.GetTVTrailers(c.Request.Context(), c.Param("tvID"))
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch TV show trailers")
+			return
+		}
+
+		c.JSON(http.StatusOK, trailers)
+	}
+}
+
+// GetTVSeason retrieves a season of a TV show with its episodes.
+func GetTVSeason(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seasonNumber, err := strconv.Atoi(c.Param("seasonNumber"))
+		if err != nil || seasonNumber < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid season number"})
+			return
+		}
+
+		season, err := client.GetTVSeason(c.Request.Context(), c.Param("tvID"), seasonNumber)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch TV season")
+			return
+		}
+
+		c.JSON(http.StatusOK, season)
+	}
+}
+
+// GetTVEpisode retrieves a single episode of a TV show.
+func GetTVEpisode(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seasonNumber, err := strconv.Atoi(c.Param("seasonNumber"))
+		if err != nil || seasonNumber < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid season number"})
+			return
+		}
+
+		episodeNumber, err := strconv.Atoi(c.Param("episodeNumber"))
+		if err != nil || episodeNumber < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode number"})
+			return
+		}
+
+		episode, err := client.GetTVEpisode(c.Request.Context(), c.Param("tvID"), seasonNumber, episodeNumber)
+		if err != nil {
+			respondWithTMDBError(c, err, "Failed to fetch TV episode")
+			return
+		}
+
+		c.JSON(http.StatusOK, episode)
+	}
+}
@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"infy/models"
+	"infy/totp"
+	"infy/utils"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	twoFactorIssuer       = "Infy"
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+
+	// twoFactorChallengeAudience keeps challenge tokens from being accepted anywhere else
+	twoFactorChallengeAudience = "2fa"
+)
+
+// newTwoFactorChallenge returns a short lived token proving the user passed the first login step.
+func newTwoFactorChallenge(user *models.User) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Subject:   user.ID.Hex(),
+		Audience:  jwt.ClaimStrings{twoFactorChallengeAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(twoFactorChallengeTTL)),
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(utils.GetEnv("JWT_SECRET_KEY", "")))
+}
+
+// parseTwoFactorChallenge returns the ID of the user a challenge token was issued to.
+func parseTwoFactorChallenge(challenge string) (string, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(challenge, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(utils.GetEnv("JWT_SECRET_KEY", "")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(twoFactorChallengeAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
+
+// adminTwoFactorRequired reports whether staff accounts such as moderators and admins must enable two-factor authentication.
+func adminTwoFactorRequired() bool {
+	return utils.GetEnv("REQUIRE_ADMIN_2FA", "false") == "true"
+}
+
+// newRecoveryCodes returns a set of recovery codes to show the user once and the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buf)[:10])
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, utils.HashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode removes the formatting users may type along with a recovery code.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// verifyTwoFactorCode checks a TOTP code of the user, rejecting codes that were already used.
+func verifyTwoFactorCode(c *gin.Context, user *models.User, code string) (bool, error) {
+	step, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	err := models.UseTwoFactorStep(user.ID, step, c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// verifySecondFactor checks either a TOTP code or a recovery code of the user.
+func verifySecondFactor(c *gin.Context, user *models.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode == "" {
+		return verifyTwoFactorCode(c, user, code)
+	}
+
+	err := models.UseRecoveryCode(user.ID, utils.HashToken(normalizeRecoveryCode(recoveryCode)), c.Request.Context())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// LoginTwoFactor completes a login with a TOTP or recovery code after the first step returned a challenge.
+func LoginTwoFactor(c *gin.Context) {
+	var request struct {
+		Challenge    string `json:"challenge" binding:"required"`
+		Code         string `json:"code" binding:"required_without=RecoveryCode"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	userID, err := parseTwoFactorChallenge(request.Challenge)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please log in again"})
+		return
+	}
+
+	user, err := models.FindUserByID(userID, c.Request.Context())
+	if err != nil || !user.TwoFactor.Enabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please log in again"})
+		return
+	}
+
+	// Codes are short, so guessing them is limited by the same lockout as passwords
+	retryAfter, err := loginRetryAfter(c, user.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	if retryAfter > 0 {
+		if _, err := recordLoginFailure(c, user.Email, user, models.LoginFailureLocked); err != nil {
+			log.Println(err)
+		}
+
+		tooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
+	ok, err := verifySecondFactor(c, user, request.Code, request.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	if !ok {
+		retryAfter, err := recordLoginFailure(c, user.Email, user, models.LoginFailureInvalidTwoFactorCode)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+			log.Println(err)
+			return
+		}
+
+		if retryAfter > 0 {
+			tooManyLoginAttempts(c, retryAfter)
+			return
+		}
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	clearLoginFailures(c, user.Email)
+
+	if err := startSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Logged in"})
+}
+
+// EnrollTwoFactor generates a new secret for the authenticated user and returns the otpauth URI to scan.
+func EnrollTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	if user.TwoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	if err := models.SetPendingTwoFactorSecret(user.ID, secret, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start enrollment"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "otpauth_uri": totp.URI(twoFactorIssuer, user.Email, secret)})
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves their app generates valid codes,
+// returning the recovery codes. They are only shown this once.
+func ConfirmTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if user.TwoFactor.PendingSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start the enrollment first"})
+		return
+	}
+
+	step, ok := totp.Validate(user.TwoFactor.PendingSecret, request.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	if err := models.EnableTwoFactor(user.ID, user.TwoFactor.PendingSecret, step, hashes, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not enable two-factor authentication"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Two-factor authentication enabled", "recovery_codes": codes})
+}
+
+// DisableTwoFactor turns off two-factor authentication after checking the password and a current code. Accounts
+// without a password log in again at the provider instead of entering one.
+func DisableTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var request struct {
+		Password     string `json:"password"`
+		Code         string `json:"code" binding:"required_without=RecoveryCode"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if !user.TwoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if user.IsStaff() && adminTwoFactorRequired() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for staff accounts"})
+		return
+	}
+
+	// Wrong passwords and codes get the same answer, so a guess does not tell which of them was wrong
+	const incorrect = "Password or code is incorrect"
+
+	if user.Password == "" {
+		if !confirmCurrentPassword(c, user, "", "") {
+			return
+		}
+	} else if !confirmSecret(c, user, models.LoginFailureInvalidPassword, "code", incorrect, func() (bool, error) {
+		return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)) == nil, nil
+	}) {
+		return
+	}
+
+	if !confirmSecret(c, user, models.LoginFailureInvalidTwoFactorCode, "code", incorrect, func() (bool, error) {
+		return verifySecondFactor(c, user, request.Code, request.RecoveryCode)
+	}) {
+		return
+	}
+
+	if err := models.DisableTwoFactor(user.ID, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not disable two-factor authentication"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the authenticated user after checking a current code.
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if !user.TwoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	// Wrong codes count towards the login lockout so a stolen session cannot be used to guess them
+	if !confirmSecret(c, user, models.LoginFailureInvalidTwoFactorCode, "code", "Code is incorrect", func() (bool, error) {
+		return verifyTwoFactorCode(c, user, request.Code)
+	}) {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred"})
+		log.Println(err)
+		return
+	}
+
+	if err := models.SetRecoveryCodes(user.ID, hashes, c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not regenerate recovery codes"})
+		log.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
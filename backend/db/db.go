@@ -62,3 +62,48 @@ func PollsCollection() *mongo.Collection {
 func ReportedPostsCollection() *mongo.Collection {
 	return client.Database("infy").Collection("reported_posts")
 }
+
+// MoviesCollection returns the local movie catalog collection
+func MoviesCollection() *mongo.Collection {
+	return client.Database("infy").Collection("movies")
+}
+
+// TMDBCacheCollection returns the collection used to cache TMDB responses
+func TMDBCacheCollection() *mongo.Collection {
+	return client.Database("infy").Collection("tmdb_cache")
+}
+
+// SessionsCollection returns the collection of login sessions and their refresh tokens
+func SessionsCollection() *mongo.Collection {
+	return client.Database("infy").Collection("sessions")
+}
+
+// UserTokensCollection returns the collection of single use email verification and password reset tokens
+func UserTokensCollection() *mongo.Collection {
+	return client.Database("infy").Collection("user_tokens")
+}
+
+// APITokensCollection returns the collection of personal access tokens
+func APITokensCollection() *mongo.Collection {
+	return client.Database("infy").Collection("api_tokens")
+}
+
+// LoginLocksCollection returns the collection of failed login counters and lockouts per account and client IP
+func LoginLocksCollection() *mongo.Collection {
+	return client.Database("infy").Collection("login_locks")
+}
+
+// LoginAttemptsCollection returns the collection of failed logins shown to the account owner
+func LoginAttemptsCollection() *mongo.Collection {
+	return client.Database("infy").Collection("login_attempts")
+}
+
+// PollVotesCollection returns the collection recording which option each user voted for
+func PollVotesCollection() *mongo.Collection {
+	return client.Database("infy").Collection("poll_votes")
+}
+
+// DataExportsCollection returns the collection of personal data export jobs
+func DataExportsCollection() *mongo.Collection {
+	return client.Database("infy").Collection("data_exports")
+}
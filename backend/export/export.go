@@ -0,0 +1,364 @@
+// Package export builds the archive of a user's personal data: a JSON document with everything
+// plus CSV files of the lists that are easier to read in a spreadsheet.
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"infy/db"
+	"infy/models"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Data is everything stored about a user.
+type Data struct {
+	ExportedAt    time.Time              `json:"exported_at"`
+	Account       Account                `json:"account"`
+	Watched       []MediaItem            `json:"watched"`
+	Watchlist     []MediaItem            `json:"watchlist"`
+	Followers     []UserRef              `json:"followers"`
+	Following     []UserRef              `json:"following"`
+	Posts         []Post                 `json:"posts"`
+	Comments      []Comment              `json:"comments"`
+	PollVotes     []*models.PollVote     `json:"poll_votes"`
+	Sessions      []*models.Session      `json:"sessions"`
+	APITokens     []*models.APIToken     `json:"api_tokens"`
+	LoginAttempts []*models.LoginAttempt `json:"failed_logins"`
+}
+
+// Account holds the account and profile settings of the user.
+type Account struct {
+	ID               string            `json:"id"`
+	Username         string            `json:"username"`
+	Email            string            `json:"email"`
+	EmailVerified    bool              `json:"email_verified"`
+	Role             string            `json:"role"`
+	TwoFactorEnabled bool              `json:"two_factor_enabled"`
+	Identities       []models.Identity `json:"identities"`
+	FirstName        string            `json:"first_name"`
+	LastName         string            `json:"last_name"`
+	DateOfBirth      time.Time         `json:"date_of_birth"`
+	Avatar           string            `json:"avatar,omitempty"`
+	Rank             string            `json:"rank"`
+	Genres           []string          `json:"genres"`
+	Region           string            `json:"region,omitempty"`
+	Services         []int             `json:"services,omitempty"`
+}
+
+// MediaItem is a movie or TV show on one of the user's lists.
+type MediaItem struct {
+	MediaType string `json:"media_type"`
+	MediaID   string `json:"media_id"`
+}
+
+// UserRef identifies another user.
+type UserRef struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// Post is a post written by the user with the number of reactions it received.
+type Post struct {
+	ID        string         `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Movie     *models.Movie  `json:"movie"`
+	Content   string         `json:"content"`
+	Reactions map[string]int `json:"reactions"` // Number of reactions of each type
+}
+
+// Comment is a comment written by the user.
+type Comment struct {
+	ID        string         `json:"id"`
+	PostID    string         `json:"post_id"`
+	ParentID  string         `json:"parent_id,omitempty"` // The comment it replies to
+	CreatedAt time.Time      `json:"created_at"`
+	Content   string         `json:"content"`
+	Reactions map[string]int `json:"reactions"` // Number of reactions of each type
+}
+
+// Collect gathers the data stored about the user.
+func Collect(ctx context.Context, user *models.User) (*Data, error) {
+	data := &Data{
+		ExportedAt: time.Now(),
+		Account:    newAccount(user),
+		Watched:    mediaItems(user.Profile.Preferences.Watched),
+		Watchlist:  mediaItems(user.Profile.Preferences.WatchList),
+		Posts:      []Post{},
+		Comments:   []Comment{},
+	}
+
+	var err error
+	if data.Followers, err = userRefs(ctx, user.Profile.Preferences.Followers); err != nil {
+		return nil, err
+	}
+	if data.Following, err = userRefs(ctx, user.Profile.Preferences.Following); err != nil {
+		return nil, err
+	}
+
+	// Go through all pages of the user's posts
+	postStore := models.PostStore{Collection: db.PostsCollection()}
+	page := models.Page{Limit: models.MaxPageLimit}
+	for {
+		posts, next, err := postStore.FindPostsByUserID(user.ID.Hex(), user.ID, ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			data.Posts = append(data.Posts, Post{ID: post.ID.Hex(), CreatedAt: post.ID.Timestamp(), Movie: post.Movie, Content: post.Content, Reactions: post.ReactionCounts})
+		}
+
+		if next == "" {
+			break
+		}
+		if page, err = models.NewPage(page.Limit, next); err != nil {
+			return nil, err
+		}
+	}
+
+	comments, err := models.FindCommentsByUserID(user.ID, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		var parentID string
+		if comment.ParentID != nil {
+			parentID = comment.ParentID.Hex()
+		}
+
+		data.Comments = append(data.Comments, Comment{
+			ID:        comment.ID.Hex(),
+			PostID:    comment.PostID.Hex(),
+			ParentID:  parentID,
+			CreatedAt: comment.ID.Timestamp(),
+			Content:   comment.Content,
+			Reactions: comment.ReactionCounts,
+		})
+	}
+
+	if data.PollVotes, err = models.FindPollVotesByUserID(user.ID, ctx); err != nil {
+		return nil, err
+	}
+
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	if data.Sessions, err = sessionStore.FindActiveSessionsByUserID(user.ID, ctx); err != nil {
+		return nil, err
+	}
+
+	tokenStore := models.APITokenStore{Collection: db.APITokensCollection()}
+	if data.APITokens, err = tokenStore.FindActiveTokensByUserID(user.ID, ctx); err != nil {
+		return nil, err
+	}
+
+	attemptStore := models.LoginAttemptStore{Collection: db.LoginAttemptsCollection()}
+	if data.LoginAttempts, err = attemptStore.FindAttemptsByUserID(user.ID, 0, ctx); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// newAccount copies the account settings of the user, leaving out secrets like the password hash.
+func newAccount(user *models.User) Account {
+	identities := user.Identities
+	if identities == nil {
+		identities = []models.Identity{}
+	}
+
+	return Account{
+		ID:               user.ID.Hex(),
+		Username:         user.Username,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerified,
+		Role:             user.Role,
+		TwoFactorEnabled: user.TwoFactor.Enabled,
+		Identities:       identities,
+		FirstName:        user.Profile.FirstName,
+		LastName:         user.Profile.LastName,
+		DateOfBirth:      user.Profile.DateOfBirth,
+		Avatar:           user.Profile.Avatar,
+		Rank:             user.Profile.Rank,
+		Genres:           user.Profile.Preferences.Genres,
+		Region:           user.Profile.Preferences.Region,
+		Services:         user.Profile.Preferences.Services,
+	}
+}
+
+// mediaItems splits the keys of a watch list into media types and IDs.
+func mediaItems(keys []string) []MediaItem {
+	items := make([]MediaItem, 0, len(keys))
+	for _, key := range keys {
+		mediaType, mediaID := models.ParseMediaKey(key)
+		items = append(items, MediaItem{MediaType: mediaType, MediaID: mediaID})
+	}
+
+	return items
+}
+
+// userRefs looks up the usernames of the given users, skipping users that no longer exist.
+func userRefs(ctx context.Context, ids []primitive.ObjectID) ([]UserRef, error) {
+	users, err := models.FindUsersByIDs(ids, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]UserRef, 0, len(users))
+	for _, user := range users {
+		refs = append(refs, UserRef{ID: user.ID.Hex(), Username: user.Username})
+	}
+
+	return refs, nil
+}
+
+// WriteArchive writes the data as a zip archive with data.json, a CSV file per list and the avatar
+// at avatarPath if it is not empty.
+func WriteArchive(w io.Writer, data *Data, avatarPath string) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return err
+	}
+
+	for _, table := range tables(data) {
+		if err := writeCSV(archive, table.name, table.rows); err != nil {
+			return err
+		}
+	}
+
+	if avatarPath != "" {
+		if err := writeFile(archive, "avatar/"+filepath.Base(avatarPath), avatarPath); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+type table struct {
+	name string
+	rows [][]string // The first row is the header
+}
+
+// tables returns the CSV files of the archive.
+func tables(data *Data) []table {
+	watched := [][]string{{"media_type", "media_id"}}
+	for _, item := range data.Watched {
+		watched = append(watched, []string{item.MediaType, item.MediaID})
+	}
+
+	watchlist := [][]string{{"media_type", "media_id"}}
+	for _, item := range data.Watchlist {
+		watchlist = append(watchlist, []string{item.MediaType, item.MediaID})
+	}
+
+	followers := [][]string{{"id", "username"}}
+	for _, ref := range data.Followers {
+		followers = append(followers, []string{ref.ID, ref.Username})
+	}
+
+	following := [][]string{{"id", "username"}}
+	for _, ref := range data.Following {
+		following = append(following, []string{ref.ID, ref.Username})
+	}
+
+	posts := [][]string{{"id", "created_at", "media_type", "media_id", "title", "content", "reactions"}}
+	for _, post := range data.Posts {
+		var mediaType, mediaID, title string
+		if post.Movie != nil {
+			mediaType, mediaID, title = post.Movie.MediaType, strconv.Itoa(post.Movie.ID), post.Movie.Title
+		}
+
+		posts = append(posts, []string{post.ID, formatTime(post.CreatedAt), mediaType, mediaID, title, post.Content, formatReactions(post.Reactions)})
+	}
+
+	comments := [][]string{{"id", "post_id", "parent_id", "created_at", "content", "reactions"}}
+	for _, comment := range data.Comments {
+		comments = append(comments, []string{comment.ID, comment.PostID, comment.ParentID, formatTime(comment.CreatedAt), comment.Content, formatReactions(comment.Reactions)})
+	}
+
+	pollVotes := [][]string{{"poll_id", "option_id", "created_at"}}
+	for _, vote := range data.PollVotes {
+		pollVotes = append(pollVotes, []string{vote.PollID, vote.OptionID, formatTime(vote.CreatedAt)})
+	}
+
+	return []table{
+		{name: "watched.csv", rows: watched},
+		{name: "watchlist.csv", rows: watchlist},
+		{name: "followers.csv", rows: followers},
+		{name: "following.csv", rows: following},
+		{name: "posts.csv", rows: posts},
+		{name: "comments.csv", rows: comments},
+		{name: "poll_votes.csv", rows: pollVotes},
+	}
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatReactions lists the number of reactions of each type, like "laugh=1 like=2", sorted by type
+func formatReactions(counts map[string]int) string {
+	types := make([]string, 0, len(counts))
+	for reactionType := range counts {
+		types = append(types, reactionType)
+	}
+	sort.Strings(types)
+
+	parts := make([]string, 0, len(types))
+	for _, reactionType := range types {
+		parts = append(parts, reactionType+"="+strconv.Itoa(counts[reactionType]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func writeCSV(archive *zip.Writer, name string, rows [][]string) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+// writeFile copies a file into the archive, skipping it if it no longer exists.
+func writeFile(archive *zip.Writer, name, path string) error {
+	source, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, source)
+
+	return err
+}
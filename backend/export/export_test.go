@@ -0,0 +1,87 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"infy/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func readArchive(t *testing.T, buf *bytes.Buffer) map[string][]byte {
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		assert.Nil(t, err)
+
+		content, err := io.ReadAll(rc)
+		assert.Nil(t, err)
+		rc.Close()
+
+		files[file.Name] = content
+	}
+
+	return files
+}
+
+func TestWriteArchive(t *testing.T) {
+	user := models.NewUser("moviefan", "fan@example.com", "hash", models.NewProfile("Ada", "Lovelace", time.Time{}, models.NewPreferences()))
+	user.Profile.Preferences.Watched = []string{"550", "tv:1399"}
+
+	data := &Data{
+		Account:   newAccount(user),
+		Watched:   mediaItems(user.Profile.Preferences.Watched),
+		Watchlist: []MediaItem{},
+		Posts: []Post{{
+			ID:        primitive.NewObjectID().Hex(),
+			Movie:     &models.Movie{ID: 550, Title: "Fight Club", MediaType: models.MediaTypeMovie},
+			Content:   "First rule, with a \"quote\", and a comma",
+			Reactions: map[string]int{"like": 2, "dislike": 1, "must_watch": 3},
+		}},
+	}
+
+	avatarPath := filepath.Join(t.TempDir(), "avatar.png")
+	assert.Nil(t, os.WriteFile(avatarPath, []byte("png"), 0o600))
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteArchive(&buf, data, avatarPath))
+
+	files := readArchive(t, &buf)
+	assert.Contains(t, files, "data.json")
+	assert.Contains(t, files, "poll_votes.csv")
+	assert.Equal(t, []byte("png"), files["avatar/avatar.png"])
+
+	// Secrets such as the password hash are never exported
+	assert.NotContains(t, string(files["data.json"]), "hash")
+
+	var decoded Data
+	assert.Nil(t, json.Unmarshal(files["data.json"], &decoded))
+	assert.Equal(t, "moviefan", decoded.Account.Username)
+	assert.Equal(t, []MediaItem{{models.MediaTypeMovie, "550"}, {models.MediaTypeTV, "1399"}}, decoded.Watched)
+
+	posts, err := csv.NewReader(bytes.NewReader(files["posts.csv"])).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, posts, 2)
+	assert.Equal(t, data.Posts[0].Content, posts[1][5])
+	assert.Equal(t, []string{"dislike=1 like=2 must_watch=3"}, posts[1][6:])
+}
+
+func TestWriteArchiveWithoutAvatar(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteArchive(&buf, &Data{}, filepath.Join(t.TempDir(), "missing.png")))
+
+	files := readArchive(t, &buf)
+	assert.Len(t, files, 8)
+}
@@ -0,0 +1,138 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"infy/utils"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv creates the mailer selected by the MAILER environment variable: smtp, file or log. The log mailer writes
+// the verification and reset links to the logs, so it is only the default in development (ENV=dev) and MAILER must
+// be set everywhere else.
+func FromEnv() (Mailer, error) {
+	name := utils.GetEnv("MAILER", "")
+	if name == "" {
+		if !utils.IsDev() {
+			return nil, fmt.Errorf("mailer: MAILER must be set outside of development")
+		}
+		name = "log"
+	}
+
+	switch name {
+	case "smtp":
+		host := utils.GetEnv("SMTP_HOST", "")
+		if host == "" {
+			return nil, fmt.Errorf("mailer: SMTP_HOST must be set")
+		}
+
+		return &SMTPMailer{
+			Host:     host,
+			Port:     utils.GetEnv("SMTP_PORT", "587"),
+			Username: utils.GetEnv("SMTP_USERNAME", ""),
+			Password: utils.GetEnv("SMTP_PASSWORD", ""),
+			From:     utils.GetEnv("MAIL_FROM", "no-reply@infy.local"),
+		}, nil
+	case "file":
+		return &FileMailer{Dir: utils.GetEnv("MAIL_DIR", "mail")}, nil
+	case "log":
+		return &LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown mailer %q", name)
+	}
+}
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send sends the message, authenticating with the server if a username is configured
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// FileMailer writes every email to its own file in a directory, for local development and tests
+type FileMailer struct {
+	Dir string
+}
+
+// Send writes the message to a new file in the mail directory
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, format("no-reply@infy.local", msg), 0o600); err != nil {
+		return err
+	}
+
+	log.Printf("Wrote email to %s", path)
+
+	return nil
+}
+
+// LogMailer logs emails instead of sending them
+type LogMailer struct{}
+
+// Send logs the message
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("Email to %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+
+	return nil
+}
+
+// headerReplacer strips line breaks so header values cannot inject extra headers
+var headerReplacer = strings.NewReplacer("\r", "", "\n", "")
+
+// format builds the raw email with its headers
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + headerReplacer.Replace(from) + "\r\n")
+	b.WriteString("To: " + headerReplacer.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + headerReplacer.Replace(msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	return []byte(b.String())
+}
+
+// sanitize keeps the characters of an email address that are safe in file names
+func sanitize(address string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, address)
+}
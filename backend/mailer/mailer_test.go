@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailerWritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	mail := &FileMailer{Dir: dir}
+
+	err := mail.Send(context.TODO(), Message{To: "test@example.com", Subject: "Reset your password", Body: "Token: abc"})
+	assert.Nil(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*test_example.com.eml"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(content), "Subject: Reset your password\r\n"))
+	assert.True(t, strings.HasSuffix(string(content), "Token: abc"))
+}
+
+func TestFromEnvRequiresMailerOutsideDev(t *testing.T) {
+	t.Setenv("MAILER", "")
+	t.Setenv("ENV", "prod")
+
+	_, err := FromEnv()
+	assert.NotNil(t, err)
+
+	// Development logs the emails unless another mailer is chosen
+	t.Setenv("ENV", "dev")
+	mail, err := FromEnv()
+	assert.Nil(t, err)
+	assert.IsType(t, &LogMailer{}, mail)
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/joho/godotenv"
+	"infy/controllers"
 	"infy/db"
+	"infy/mailer"
+	"infy/models"
+	"infy/oidc"
 	"infy/routes"
+	"infy/tmdb"
 	"infy/utils"
+	"infy/validation"
 	"log"
+	"time"
 )
 
 func main() {
@@ -20,9 +28,139 @@ func main() {
 	db.InitMongo()
 	defer db.CloseMongo()
 
+	// Existing accounts may share an email or username in different casing, which has to be cleaned up by hand
+	if err := models.EnsureUserIndexes(context.Background()); err != nil {
+		log.Fatalf("Could not create the unique user indexes: %v", err)
+	}
+
+	// Users saved before roles existed get a role from their old admin flag
+	if migrated, err := models.MigrateAdminRoles(context.Background()); err != nil {
+		log.Fatal(err)
+	} else if migrated > 0 {
+		log.Printf("Gave %d users a role", migrated)
+	}
+
+	// Posts and comments saved with a copy of their author only keep the author's ID
+	if migrated, err := models.MigrateAuthorship(context.Background()); err != nil {
+		log.Fatal(err)
+	} else if migrated > 0 {
+		log.Printf("Removed the embedded author from %d posts and comments", migrated)
+	}
+
+	// Likes and dislikes saved before reactions had a type become like and dislike reactions
+	if migrated, err := models.MigrateTypedReactions(context.Background()); err != nil {
+		log.Fatal(err)
+	} else if migrated > 0 {
+		log.Printf("Migrated the reactions of %d posts and comments", migrated)
+	}
+
+	if email := utils.GetEnv("BOOTSTRAP_SUPERADMIN_EMAIL", ""); email != "" {
+		if err := models.PromoteToSuperadmin(email, context.Background()); err != nil {
+			log.Printf("Could not promote %s to superadmin: %v", email, err)
+		}
+	}
+
+	sessionStore := &models.SessionStore{Collection: db.SessionsCollection()}
+	if err := sessionStore.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Configuring TMDB client...")
+	tmdbConfig, err := tmdb.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if utils.GetEnv("TMDB_CACHE", "memory") == "mongo" {
+		mongoCache := &tmdb.MongoCache{Collection: db.TMDBCacheCollection()}
+		if err := mongoCache.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		tmdbConfig.Cache = mongoCache
+	}
+
+	movieStore := &models.MovieStore{Collection: db.MoviesCollection()}
+	if err := movieStore.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	tmdbConfig.Catalog = movieStore
+
+	tmdbClient, err := tmdb.NewClient(tmdbConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	refreshInterval, err := time.ParseDuration(utils.GetEnv("CATALOG_REFRESH_INTERVAL", "1h"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	maxAge, err := time.ParseDuration(utils.GetEnv("CATALOG_MAX_AGE", "168h"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Keep the local movie catalog fresh in the background
+	go tmdbClient.RunCatalogRefresh(context.Background(), refreshInterval, maxAge, 50)
+
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tokenStore := &models.UserTokenStore{Collection: db.UserTokensCollection()}
+	if err := tokenStore.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	apiTokenStore := &models.APITokenStore{Collection: db.APITokensCollection()}
+	if err := apiTokenStore.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	loginLockStore := &models.LoginLockStore{Collection: db.LoginLocksCollection()}
+	if err := loginLockStore.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	loginAttemptStore := &models.LoginAttemptStore{Collection: db.LoginAttemptsCollection()}
+	if err := loginAttemptStore.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	policy, err := validation.PasswordPolicyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reactions, err := models.ReactionSetFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var provider *oidc.Provider
+	if oidcConfig, ok := oidc.ConfigFromEnv(); ok {
+		provider, err = oidc.NewProvider(oidcConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	exportStore := &models.DataExportStore{Collection: db.DataExportsCollection()}
+	if err := exportStore.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	purgeInterval := utils.GetEnvDuration("ACCOUNT_PURGE_INTERVAL", time.Hour)
+	go controllers.RunAccountPurge(context.Background(), purgeInterval)
+	go controllers.RunExportCleanup(context.Background(), purgeInterval)
+
 	fmt.Println("Starting server...")
 	port := ":" + utils.GetEnv("PORT", "8000")
-	r := routes.InitRoutes()
+	r, err := routes.InitRoutes(tmdbClient, mail, provider, policy, reactions)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	err = r.Run(port)
 	if err != nil {
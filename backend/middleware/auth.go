@@ -2,27 +2,35 @@ package middleware
 
 import (
 	"errors"
+	"infy/db"
 	"infy/models"
 	"infy/utils"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Authorized checks if the user is authorized by verifying the JWT token.
+// Authorized checks if the user is authorized by verifying the session token cookie or the Authorization header.
+// Requests authenticated with a personal access token need the read scope for safe methods and the write scope otherwise.
 func Authorized() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := c.Cookie("token") // Attempt to retrieve the JWT token from the cookie.
-		if err != nil {
-			c.JSON(401, gin.H{"error": "Unauthorized"}) // Send an unauthorized error if no token is found.
+		user, err := authenticate(c) // Resolve the user from the cookie or the Authorization header.
+		if err != nil || user == nil {
+			c.JSON(401, gin.H{"error": "Unauthorized"}) // Respond with unauthorized if the token is missing, invalid or the user does not exist.
 			c.Abort()
 			return
 		}
 
-		user, err := GetUserFromToken(token, c) // Decode the token to retrieve the user.
-		if err != nil || user == nil {
-			c.JSON(401, gin.H{"error": "Unauthorized"}) // Respond with unauthorized if the token is invalid or user does not exist.
+		scope := models.ScopeWrite
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			scope = models.ScopeRead
+		}
+
+		if !hasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token is missing the " + scope + " scope"})
 			c.Abort()
 			return
 		}
@@ -32,14 +40,109 @@ func Authorized() gin.HandlerFunc {
 	}
 }
 
+// RequireScope checks that requests authenticated with a personal access token have the scope.
+// Requests authenticated with a session have every scope. It must run after Authorized.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token is missing the " + scope + " scope"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated with a personal access token, so tokens cannot manage
+// credentials such as other tokens, sessions or two-factor settings. It must run after Authorized.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isToken := c.Get("token_scopes"); isToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not available with API tokens"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalUser returns the authenticated user, or nil for anonymous requests and requests with invalid tokens.
+func OptionalUser(c *gin.Context) *models.User {
+	user, err := authenticate(c)
+	if err != nil {
+		return nil
+	}
+
+	return user
+}
+
+// hasScope checks the scopes of the personal access token the request was authenticated with, if any.
+func hasScope(c *gin.Context, scope string) bool {
+	scopes, isToken := c.Get("token_scopes")
+	return !isToken || models.HasScope(scopes.([]string), scope)
+}
+
+// authenticate resolves the user from the token cookie, falling back to a Bearer token in the Authorization header.
+// Bearer tokens are either session access tokens or personal access tokens.
+func authenticate(c *gin.Context) (*models.User, error) {
+	token, err := c.Cookie("token")
+	if err != nil || token == "" {
+		var found bool
+		token, found = strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || token == "" {
+			return nil, errors.New("no token")
+		}
+
+		if strings.HasPrefix(token, models.APITokenPrefix) {
+			return getUserFromAPIToken(token, c)
+		}
+	}
+
+	return GetUserFromToken(token, c)
+}
+
+// getUserFromAPIToken looks up a personal access token and returns its user, recording the token's scopes in the context.
+func getUserFromAPIToken(token string, c *gin.Context) (*models.User, error) {
+	tokenStore := models.APITokenStore{Collection: db.APITokensCollection()}
+	apiToken, err := tokenStore.FindTokenByHash(utils.HashToken(token), c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiToken.IsActive() {
+		return nil, errors.New("token is no longer active")
+	}
+
+	if err := tokenStore.TouchToken(apiToken.ID, c.Request.Context()); err != nil {
+		log.Println(err)
+	}
+
+	user, err := models.FindUserByID(apiToken.UserID.Hex(), c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens without the admin scope act as a regular user, also in handlers that check permissions themselves
+	if !models.HasScope(apiToken.Scopes, models.ScopeAdmin) {
+		user.Role = models.RoleUser
+	}
+
+	c.Set("token_scopes", apiToken.Scopes)
+
+	return user, nil
+}
+
+// GetUserFromToken parses an access token and returns its user if the session it was issued for is still active.
 func GetUserFromToken(token string, c *gin.Context) (*models.User, error) {
 	// Parse the token with the JWT_SECRET_KEY from the environment variables
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+	var claims models.AccessClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
 		jwtSecretKey := utils.GetEnv("JWT_SECRET_KEY", "") // Retrieve JWT secret key from environment.
 		return []byte(jwtSecretKey), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
-		if err.Error() == "token has invalid claims: token is expired" {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			// The client is expected to get a new access token from /auth/refresh
 			c.SetCookie("token", "", -1, "/", utils.GetEnv("SITE_DOMAIN", "localhost"), utils.IsProd(), true)
 			err = errors.New("token deleted due to expiration")
 			return nil, err
@@ -47,12 +150,20 @@ func GetUserFromToken(token string, c *gin.Context) (*models.User, error) {
 		return nil, err
 	}
 
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		userId := claims["sub"].(string)                        // Extract user ID from token claims.
-		return models.FindUserByID(userId, c.Request.Context()) // Fetch the user from the database by ID.
+	// Tokens are only valid as long as their session, so logging out revokes them immediately
+	sessionStore := models.SessionStore{Collection: db.SessionsCollection()}
+	session, err := sessionStore.FindSessionByID(claims.SessionID, c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	if !session.IsActive() || session.UserID.Hex() != claims.Subject {
+		return nil, errors.New("session is no longer active")
 	}
 
-	return nil, nil // Return nil if the token is not valid or claims are not correctly parsed.
+	c.Set("session_id", claims.SessionID)
+
+	return models.FindUserByID(claims.Subject, c.Request.Context()) // Fetch the user from the database by ID.
 }
 
 // ErrorHandler captures any errors occurred during HTTP request processing and returns them.
@@ -66,12 +177,28 @@ func ErrorHandler() gin.HandlerFunc {
 	}
 }
 
-// AdminAuthorized checks if the logged-in user has admin privileges.
-func AdminAuthorized() gin.HandlerFunc {
+// RequirePermission checks if the logged-in user's role grants all the given permissions.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		user, exists := c.Get("user")
-		if !exists || !user.(*models.User).IsAdmin {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"}) // Check if the user is an admin.
+		value, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		user := value.(*models.User)
+		for _, permission := range permissions {
+			if !user.HasPermission(permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission", "permission": permission})
+				c.Abort()
+				return
+			}
+		}
+
+		// Staff accounts can be required to use two-factor authentication
+		if utils.GetEnv("REQUIRE_ADMIN_2FA", "false") == "true" && !user.TwoFactor.Enabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for staff accounts"})
 			c.Abort()
 			return
 		}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"context"
+	"infy/db"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeletedUsername is shown in place of the author of comments left by deleted accounts
+const DeletedUsername = "[deleted]"
+
+// DeletedAuthor returns the placeholder author of content kept after its user was deleted
+func DeletedAuthor() *Author {
+	return &Author{ID: primitive.NilObjectID, Username: DeletedUsername}
+}
+
+// UpdateProfile sets the given profile fields of the user and returns the updated user
+func UpdateProfile(userID primitive.ObjectID, fields bson.M, ctx context.Context) (*User, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user User
+	err := db.UsersCollection().FindOneAndUpdate(ctx, bson.M{"_id": userID}, bson.M{"$set": fields}, opts).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdateEmail changes the user's email address, which has to be verified again
+func UpdateEmail(userID primitive.ObjectID, email string, ctx context.Context) error {
+	update := bson.M{"$set": bson.M{"email": email, "email_verified": false}}
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, update)
+
+	return err
+}
+
+// ScheduleUserDeletion marks the user to be deleted once the given time has passed
+func ScheduleUserDeletion(userID primitive.ObjectID, deleteAfter time.Time, ctx context.Context) error {
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, bson.M{"$set": bson.M{"delete_after": deleteAfter}})
+
+	return err
+}
+
+// CancelUserDeletion keeps a user that was scheduled to be deleted. It returns mongo.ErrNoDocuments if the user's
+// data is already being deleted.
+func CancelUserDeletion(userID primitive.ObjectID, ctx context.Context) error {
+	return cancelUserDeletion(db.UsersCollection(), userID, ctx)
+}
+
+// cancelUserDeletion cancels the deletion of the user in the users collection
+func cancelUserDeletion(users *mongo.Collection, userID primitive.ObjectID, ctx context.Context) error {
+	filter := bson.M{"_id": userID, "deleting": bson.M{"$ne": true}}
+	result, err := users.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"delete_after": ""}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// FindUsersDueForDeletion finds up to limit users whose deletion grace period ended before the given time
+func FindUsersDueForDeletion(before time.Time, limit int64, ctx context.Context) ([]*User, error) {
+	cursor, err := db.UsersCollection().Find(ctx, bson.M{"delete_after": bson.M{"$lte": before}}, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []*User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// DeleteUserData permanently deletes a user scheduled for deletion along with their posts, reactions, follows and
+// credentials. Comments on other users' posts are kept without an author, so they are shown as DeletedAuthor. It returns
+// mongo.ErrNoDocuments if the deletion was cancelled in the meantime.
+func DeleteUserData(user *User, ctx context.Context) error {
+	return deleteUserData(user, userDataCollections(), ctx)
+}
+
+// accountCollections are the collections a user's data is kept in
+type accountCollections struct {
+	users, posts, comments, reportedPosts, loginLocks *mongo.Collection
+	owned                                             []*mongo.Collection // Only hold documents of a single user, found by user_id
+}
+
+// userDataCollections returns the collections of the database a user's data is kept in
+func userDataCollections() accountCollections {
+	return accountCollections{
+		users:         db.UsersCollection(),
+		posts:         db.PostsCollection(),
+		comments:      db.CommentsCollection(),
+		reportedPosts: db.ReportedPostsCollection(),
+		loginLocks:    db.LoginLocksCollection(),
+		owned:         []*mongo.Collection{db.SessionsCollection(), db.UserTokensCollection(), db.APITokensCollection(), db.LoginAttemptsCollection(), db.PollVotesCollection()},
+	}
+}
+
+// deleteUserData deletes the user's data from the collections
+func deleteUserData(user *User, collections accountCollections, ctx context.Context) error {
+	// Mark the user first so a login cancelling the deletion at the same time either wins or cannot cancel it anymore
+	filter := bson.M{"_id": user.ID, "delete_after": bson.M{"$lte": time.Now()}}
+	result, err := collections.users.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"deleting": true}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	// Remove the user's posts together with their comments and reports
+	postIDs, err := collections.posts.Distinct(ctx, "_id", bson.M{"user_id": user.ID})
+	if err != nil {
+		return err
+	}
+
+	if len(postIDs) > 0 {
+		if _, err := collections.comments.DeleteMany(ctx, bson.M{"post_id": bson.M{"$in": postIDs}}); err != nil {
+			return err
+		}
+		if _, err := collections.reportedPosts.DeleteMany(ctx, bson.M{"post._id": bson.M{"$in": postIDs}}); err != nil {
+			return err
+		}
+		if _, err := collections.posts.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": postIDs}}); err != nil {
+			return err
+		}
+	}
+
+	// Keep the user's comments on other posts so the discussions still make sense, but anonymize them
+	_, err = collections.comments.UpdateMany(ctx, bson.M{"user_id": user.ID}, bson.M{"$set": bson.M{"user_id": primitive.NilObjectID}})
+	if err != nil {
+		return err
+	}
+
+	// Remove the user's reactions to posts and comments
+	if err := removeUserReactions(user.ID, []*mongo.Collection{collections.posts, collections.comments}, ctx); err != nil {
+		return err
+	}
+
+	// Remove the user from the following and followers lists of other users
+	_, err = collections.users.UpdateMany(ctx, bson.M{"profile.preferences.followers": user.ID}, bson.M{"$pull": bson.M{"profile.preferences.followers": user.ID}})
+	if err != nil {
+		return err
+	}
+	_, err = collections.users.UpdateMany(ctx, bson.M{"profile.preferences.following": user.ID}, bson.M{"$pull": bson.M{"profile.preferences.following": user.ID}})
+	if err != nil {
+		return err
+	}
+
+	// Remove everything that could still authenticate as the user or was recorded about them
+	for _, collection := range collections.owned {
+		if _, err := collection.DeleteMany(ctx, bson.M{"user_id": user.ID}); err != nil {
+			return err
+		}
+	}
+
+	lockStore := LoginLockStore{Collection: collections.loginLocks}
+	if err := lockStore.Reset(AccountLockKey(user.Email), ctx); err != nil {
+		return err
+	}
+
+	// Delete the user last, so a deletion that failed part way is found and retried until everything else is gone
+	_, err = collections.users.DeleteOne(ctx, bson.M{"_id": user.ID})
+
+	return err
+}
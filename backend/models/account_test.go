@@ -0,0 +1,90 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// testAccountCollections keeps all of a user's data in the mocked collection
+func testAccountCollections(mt *mtest.T) accountCollections {
+	return accountCollections{users: mt.Coll, posts: mt.Coll, comments: mt.Coll, reportedPosts: mt.Coll, loginLocks: mt.Coll, owned: []*mongo.Collection{mt.Coll}}
+}
+
+func TestCancelUserDeletion(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		err := cancelUserDeletion(mt.Coll, defaultUser.ID, context.TODO())
+		assert.Nil(t, err)
+
+		// A deletion that already started cannot be cancelled
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "true", update.Lookup("q", "deleting", "$ne").String())
+	})
+
+	mt.Run("already deleting", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		err := cancelUserDeletion(mt.Coll, defaultUser.ID, context.TODO())
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+}
+
+func TestDeleteUserData(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		user := &User{ID: primitive.NewObjectID(), Email: "deleted@example.com"}
+		updated := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+		mt.AddMockResponses(updated, mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{expectedPosts[0].ID}}))
+		for i := 0; i < 11; i++ {
+			mt.AddMockResponses(updated)
+		}
+
+		err := deleteUserData(user, testAccountCollections(mt), context.TODO())
+		assert.Nil(t, err)
+
+		var events []bson.Raw
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			events = append(events, event.Command)
+		}
+		assert.Len(t, events, 13)
+
+		// The user is marked first so logging in can no longer cancel the deletion
+		mark := events[0].Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "true", mark.Lookup("u", "$set", "deleting").String())
+
+		// Comments on other posts are kept without their author
+		anonymize := events[5].Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, user.ID, anonymize.Lookup("q", "user_id").ObjectID())
+		assert.Equal(t, primitive.NilObjectID, anonymize.Lookup("u", "$set", "user_id").ObjectID())
+
+		// The user is deleted last, so a deletion that fails part way is retried
+		last := events[12].Lookup("deletes").Array().Index(0).Value().Document()
+		assert.Equal(t, user.ID, last.Lookup("q", "_id").ObjectID())
+	})
+
+	mt.Run("grace period not over", func(mt *mtest.T) {
+		user := &User{ID: primitive.NewObjectID(), Email: "kept@example.com"}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		err := deleteUserData(user, testAccountCollections(mt), context.TODO())
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+		// Nothing is deleted for a user that is not due yet
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "update", event.CommandName)
+		assert.WithinDuration(t, time.Now(), event.Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q", "delete_after", "$lte").Time(), time.Minute)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APITokenPrefix starts every personal access token so they can be told apart from session tokens
+const APITokenPrefix = "infy_pat_"
+
+// Scopes of personal access tokens. Each scope includes the ones before it.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+var scopeLevels = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// HasScope checks if the granted scopes include the required scope, directly or through a broader scope
+func HasScope(granted []string, required string) bool {
+	requiredLevel := slices.Index(scopeLevels, required)
+	for _, scope := range granted {
+		if level := slices.Index(scopeLevels, scope); level >= 0 && level >= requiredLevel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APIToken is a personal access token scripts and other clients use in the Authorization header.
+// Only the hash of the token is stored.
+type APIToken struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	UserID     primitive.ObjectID `json:"-" bson:"user_id"`
+	Name       string             `json:"name" bson:"name"`
+	TokenHash  string             `json:"-" bson:"token_hash"`
+	Scopes     []string           `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt  *time.Time         `json:"expires_at" bson:"expires_at,omitempty"` // Tokens without expiry stay valid until revoked
+	LastUsedAt *time.Time         `json:"last_used_at" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `json:"-" bson:"revoked_at,omitempty"`
+}
+
+type APITokenStore struct {
+	Collection *mongo.Collection
+}
+
+// NewAPIToken creates a new token for the user, expiring after ttl unless it is zero
+func NewAPIToken(userID primitive.ObjectID, name, tokenHash string, scopes []string, ttl time.Duration) *APIToken {
+	token := &APIToken{ID: primitive.NewObjectID(), UserID: userID, Name: name, TokenHash: tokenHash, Scopes: scopes, CreatedAt: time.Now()}
+	if ttl > 0 {
+		expiresAt := token.CreatedAt.Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+
+	return token
+}
+
+// IsActive checks if the token has not been revoked and has not expired
+func (t *APIToken) IsActive() bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || time.Now().Before(*t.ExpiresAt))
+}
+
+// EnsureIndexes creates the indexes used to look up tokens and the TTL index that removes expired tokens
+func (store *APITokenStore) EnsureIndexes(ctx context.Context) error {
+	_, err := store.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+
+	return err
+}
+
+// Save saves a token to the database
+func (store *APITokenStore) Save(t *APIToken, ctx context.Context) error {
+	_, err := store.Collection.InsertOne(ctx, t)
+
+	return err
+}
+
+// FindTokenByHash finds the token with the given hash
+func (store *APITokenStore) FindTokenByHash(tokenHash string, ctx context.Context) (*APIToken, error) {
+	var token APIToken
+	err := store.Collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// FindActiveTokensByUserID finds the tokens of a user that have not been revoked or expired, newest first
+func (store *APITokenStore) FindActiveTokensByUserID(userID primitive.ObjectID, ctx context.Context) ([]*APIToken, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+		"$or":        bson.A{bson.M{"expires_at": bson.M{"$exists": false}}, bson.M{"expires_at": bson.M{"$gt": time.Now()}}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := store.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []*APIToken{}
+	if err = cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// TouchToken records that the token was just used
+func (store *APITokenStore) TouchToken(id primitive.ObjectID, ctx context.Context) error {
+	_, err := store.Collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_used_at": time.Now()}})
+
+	return err
+}
+
+// RevokeToken revokes a token of the user. It returns mongo.ErrNoDocuments if the user has no such active token.
+func (store *APITokenStore) RevokeToken(id string, userID primitive.ObjectID, ctx context.Context) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": objectID, "user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	result, err := store.Collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes every active token of the user
+func (store *APITokenStore) RevokeUserTokens(userID primitive.ObjectID, ctx context.Context) error {
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	_, err := store.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+
+	return err
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHasScope(t *testing.T) {
+	assert.True(t, HasScope([]string{ScopeRead}, ScopeRead))
+	assert.False(t, HasScope([]string{ScopeRead}, ScopeWrite))
+	assert.True(t, HasScope([]string{ScopeWrite}, ScopeRead))
+	assert.True(t, HasScope([]string{ScopeAdmin}, ScopeWrite))
+	assert.False(t, HasScope([]string{ScopeWrite}, ScopeAdmin))
+	assert.False(t, HasScope(nil, ScopeRead))
+}
+
+func TestAPITokenIsActive(t *testing.T) {
+	token := NewAPIToken(primitive.NewObjectID(), "cli", "hash", []string{ScopeRead}, 0)
+	assert.Nil(t, token.ExpiresAt)
+	assert.True(t, token.IsActive())
+
+	expiring := NewAPIToken(primitive.NewObjectID(), "cli", "hash", []string{ScopeRead}, time.Hour)
+	assert.True(t, expiring.IsActive())
+
+	expiredAt := time.Now().Add(-time.Minute)
+	expiring.ExpiresAt = &expiredAt
+	assert.False(t, expiring.IsActive())
+
+	revokedAt := time.Now()
+	token.RevokedAt = &revokedAt
+	assert.False(t, token.IsActive())
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"context"
+	"infy/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Author is the public part of a user shown with their posts and comments
+type Author struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	Username string             `json:"username" bson:"username"`
+	Profile  AuthorProfile      `json:"profile" bson:"profile"`
+}
+
+// AuthorProfile is the public part of an author's profile
+type AuthorProfile struct {
+	FirstName string `json:"first_name" bson:"first_name"`
+	LastName  string `json:"last_name" bson:"last_name"`
+	Avatar    string `json:"avatar" bson:"avatar,omitempty"`
+}
+
+// authorProjection only reads the fields of Author from a user
+var authorProjection = bson.M{"username": 1, "profile.first_name": 1, "profile.last_name": 1, "profile.avatar": 1}
+
+// Author returns the public part of the user
+func (u *User) Author() *Author {
+	return &Author{
+		ID:       u.ID,
+		Username: u.Username,
+		Profile:  AuthorProfile{FirstName: u.Profile.FirstName, LastName: u.Profile.LastName, Avatar: u.Profile.Avatar},
+	}
+}
+
+// AuthorLoader looks up the authors of posts and comments, which only store the ID of their author. It remembers
+// the authors it found, so a loader used for one request looks up every author only once.
+type AuthorLoader struct {
+	Collection *mongo.Collection
+	authors    map[primitive.ObjectID]*Author
+}
+
+// FindAuthors finds the authors with the given IDs that were not found before in one query. Authors whose account
+// was deleted are returned as DeletedAuthor.
+func (loader *AuthorLoader) FindAuthors(ids []primitive.ObjectID, ctx context.Context) (map[primitive.ObjectID]*Author, error) {
+	if loader.authors == nil {
+		loader.authors = map[primitive.ObjectID]*Author{}
+	}
+	authors := loader.authors
+
+	var missing []primitive.ObjectID
+	for _, id := range ids {
+		if _, ok := authors[id]; !ok && !id.IsZero() {
+			authors[id] = nil
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		opts := options.Find().SetProjection(authorProjection)
+		cursor, err := loader.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": missing}}, opts)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		var found []*Author
+		if err = cursor.All(ctx, &found); err != nil {
+			return nil, err
+		}
+
+		for _, author := range found {
+			authors[author.ID] = author
+		}
+	}
+
+	for _, id := range ids {
+		if authors[id] == nil {
+			authors[id] = DeletedAuthor()
+		}
+	}
+
+	return authors, nil
+}
+
+// LoadPostAuthors sets the author of each post
+func (loader *AuthorLoader) LoadPostAuthors(posts []*Post, ctx context.Context) error {
+	ids := make([]primitive.ObjectID, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.UserID)
+	}
+
+	authors, err := loader.FindAuthors(ids, ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		post.User = authors[post.UserID]
+	}
+
+	return nil
+}
+
+// LoadCommentAuthors sets the author of each comment
+func (loader *AuthorLoader) LoadCommentAuthors(comments []*Comment, ctx context.Context) error {
+	ids := make([]primitive.ObjectID, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.UserID)
+	}
+
+	authors, err := loader.FindAuthors(ids, ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		comment.User = authors[comment.UserID]
+	}
+
+	return nil
+}
+
+// LoadReactionAuthors sets the user who gave each reaction
+func (loader *AuthorLoader) LoadReactionAuthors(reactions []*Reaction, ctx context.Context) error {
+	ids := make([]primitive.ObjectID, 0, len(reactions))
+	for _, reaction := range reactions {
+		ids = append(ids, reaction.UserID)
+	}
+
+	authors, err := loader.FindAuthors(ids, ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, reaction := range reactions {
+		reaction.User = authors[reaction.UserID]
+	}
+
+	return nil
+}
+
+// MigrateAuthorship replaces the copies of the author embedded in posts, comments and reported posts saved before
+// authors were looked up when reading, keeping only the author's ID. It is safe to run on every start.
+func MigrateAuthorship(ctx context.Context) (int64, error) {
+	var migrated int64
+
+	migrations := []struct {
+		collection *mongo.Collection
+		field      string
+	}{
+		{db.PostsCollection(), "user"},
+		{db.CommentsCollection(), "user"},
+		{db.ReportedPostsCollection(), "post.user"},
+	}
+
+	for _, migration := range migrations {
+		idField := migration.field + "_id"
+		update := mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{idField: "$" + migration.field + "._id"}}},
+			{{Key: "$unset", Value: migration.field}},
+		}
+
+		result, err := migration.collection.UpdateMany(ctx, bson.M{migration.field: bson.M{"$type": "object"}}, update)
+		if err != nil {
+			return migrated, err
+		}
+		migrated += result.ModifiedCount
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAuthorIsPublic(t *testing.T) {
+	user := NewUser("moviefan", "fan@example.com", "hash", NewProfile("Ada", "Lovelace", time.Now(), NewPreferences()))
+	user.Profile.Preferences.Followers = []primitive.ObjectID{primitive.NewObjectID()}
+
+	encoded, err := json.Marshal(user.Author())
+	assert.Nil(t, err)
+	assert.NotContains(t, string(encoded), "fan@example.com")
+	assert.NotContains(t, string(encoded), "followers")
+	assert.Contains(t, string(encoded), "moviefan")
+}
+
+func TestLoadPostAuthors(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("load authors once", func(mt *mtest.T) {
+		author := &Author{ID: primitive.NewObjectID(), Username: "moviefan", Profile: AuthorProfile{Avatar: "avatar.png"}}
+		deletedID := primitive.NewObjectID()
+
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: author.ID},
+			{Key: "username", Value: author.Username},
+			{Key: "profile", Value: bson.D{{Key: "avatar", Value: author.Profile.Avatar}}},
+		}))
+
+		posts := []*Post{{UserID: author.ID}, {UserID: deletedID}, {UserID: author.ID}, {UserID: primitive.NilObjectID}}
+
+		loader := &AuthorLoader{Collection: mt.Coll}
+		assert.Nil(t, loader.LoadPostAuthors(posts, context.TODO()))
+
+		assert.Equal(t, author, posts[0].User)
+		assert.Equal(t, author, posts[2].User)
+		assert.Equal(t, DeletedAuthor(), posts[1].User)
+		assert.Equal(t, DeletedAuthor(), posts[3].User)
+
+		// Authors that were already looked up are not queried again
+		comments := []*Comment{{UserID: author.ID}}
+		assert.Nil(t, loader.LoadCommentAuthors(comments, context.TODO()))
+		assert.Equal(t, author, comments[0].User)
+	})
+}
@@ -2,8 +2,10 @@ package models
 
 import (
 	"context"
+	"errors"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"infy/db"
@@ -12,20 +14,67 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Limits of comment threads
+const (
+	MaxCommentDepth  = 5 // Depth of the deepest replies, top-level comments have depth 0
+	InlineReplyLimit = 3 // Number of replies shown with each comment before they have to be paged through
+)
+
+// ErrCommentTooDeep is returned when replying to a comment that is already at the maximum depth
+var ErrCommentTooDeep = errors.New("comment is too deeply nested to reply to")
+
 type Comment struct {
-	ID         primitive.ObjectID   `json:"id" bson:"_id"`
-	PostID     primitive.ObjectID   `json:"post_id" bson:"post_id"`
-	User       *User                `json:"user" bson:"user"`
-	Likes      int                  `json:"likes"`
-	Dislikes   int                  `json:"dislikes"`
-	LikedBy    []primitive.ObjectID `bson:"liked_by" json:"liked_by,omitempty"`
-	DislikedBy []primitive.ObjectID `bson:"disliked_by" json:"disliked_by,omitempty"`
-	Content    string               `json:"content"`
+	ID                primitive.ObjectID  `json:"id" bson:"_id"`
+	PostID            primitive.ObjectID  `json:"post_id" bson:"post_id"`
+	ParentID          *primitive.ObjectID `json:"parent_id" bson:"parent_id,omitempty"` // Nil for top-level comments
+	Depth             int                 `json:"depth" bson:"depth"`
+	ReplyCount        int                 `json:"reply_count" bson:"reply_count"`
+	Replies           []*Comment          `json:"replies,omitempty" bson:"-"`             // The first replies, set by LoadReplies
+	RepliesNextCursor string              `json:"replies_next_cursor,omitempty" bson:"-"` // Cursor of the replies after Replies, set by LoadReplies
+	Deleted           bool                `json:"deleted" bson:"deleted,omitempty"`       // Deleted comments that have replies are kept without content
+	UserID            primitive.ObjectID  `json:"-" bson:"user_id"`
+	User              *Author             `json:"user" bson:"-"` // Set by AuthorLoader when reading
+	Reactions         []Reaction          `json:"-" bson:"reactions"`
+	ReactionCounts    map[string]int      `json:"reaction_counts" bson:"reaction_counts"` // Kept in sync with Reactions by ReactionStore
+	Likes             int                 `json:"likes" bson:"likes,omitempty"`           // Read from ReactionCounts by commentViewProjection, never stored
+	Dislikes          int                 `json:"dislikes" bson:"dislikes,omitempty"`     // Read from ReactionCounts by commentViewProjection, never stored
+	ViewerReaction    string              `json:"reaction" bson:"viewer_reaction,omitempty"`
+	Content           string              `json:"content"`
 }
 
 // NewComment creates a new comment instance
 func NewComment(postID primitive.ObjectID, user *User, content string) *Comment {
-	return &Comment{ID: primitive.NewObjectID(), PostID: postID, User: user, Likes: 0, Dislikes: 0, Content: content}
+	return &Comment{ID: primitive.NewObjectID(), PostID: postID, UserID: user.ID, User: user.Author(), ReactionCounts: map[string]int{}, Content: content}
+}
+
+// NewReply creates a reply to a comment, unless the comment is already at the maximum depth
+func NewReply(parent *Comment, user *User, content string) (*Comment, error) {
+	if parent.Depth >= MaxCommentDepth {
+		return nil, ErrCommentTooDeep
+	}
+
+	reply := NewComment(parent.PostID, user, content)
+	reply.ParentID = &parent.ID
+	reply.Depth = parent.Depth + 1
+
+	return reply, nil
+}
+
+// commentViewProjection reads a comment with the viewer's reaction in place of the reactions of all users
+func commentViewProjection(viewerID primitive.ObjectID) bson.M {
+	return bson.M{
+		"post_id":         1,
+		"parent_id":       1,
+		"depth":           1,
+		"reply_count":     1,
+		"deleted":         1,
+		"user_id":         1,
+		"reaction_counts": 1,
+		"likes":           bson.M{"$ifNull": bson.A{"$reaction_counts." + ReactionLike, 0}},
+		"dislikes":        bson.M{"$ifNull": bson.A{"$reaction_counts." + ReactionDislike, 0}},
+		"viewer_reaction": viewerReaction(viewerID),
+		"content":         1,
+	}
 }
 
 // FindCommentByID finds comments by comment ID
@@ -44,24 +93,93 @@ func FindCommentsByID(id string, ctx context.Context) (*Comment, error) {
 	return &comment, err
 }
 
-// FindCommentsByPostID finds comments by post ID
-func FindCommentsByPostID(postID string, ctx context.Context, limit int64) ([]*Comment, error) {
-	postObjectID, _ := primitive.ObjectIDFromHex(postID)
+// FindCommentsByPostID finds a page of the top-level comments on a post, newest first, with the reaction of the viewer
+func FindCommentsByPostID(postID, viewerID primitive.ObjectID, ctx context.Context, page Page) ([]*Comment, string, error) {
+	filter := bson.M{"post_id": postID, "parent_id": nil}
 
-	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: -1}}).SetLimit(limit)
-	cursor, err := db.CommentsCollection().Find(ctx, bson.M{"post_id": postObjectID}, opts)
+	return findPage[Comment](ctx, db.CommentsCollection(), filter, "", commentViewProjection(viewerID), page)
+}
+
+// FindReplies finds a page of the replies to a comment, newest first, with the reaction of the viewer
+func FindReplies(parentID, viewerID primitive.ObjectID, ctx context.Context, page Page) ([]*Comment, string, error) {
+	return findPage[Comment](ctx, db.CommentsCollection(), bson.M{"parent_id": parentID}, "", commentViewProjection(viewerID), page)
+}
+
+// LoadReplies sets the first InlineReplyLimit replies of each comment that has replies, finding them all in one
+// query. Comments with more replies get the cursor to page through the rest with FindReplies.
+func LoadReplies(comments []*Comment, viewerID primitive.ObjectID, ctx context.Context) error {
+	var parentIDs []primitive.ObjectID
+	for _, comment := range comments {
+		if comment.ReplyCount > 0 {
+			parentIDs = append(parentIDs, comment.ID)
+		}
+	}
+
+	if len(parentIDs) == 0 {
+		return nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"parent_id": bson.M{"$in": parentIDs}}}},
+		{{Key: "$sort", Value: pageSort("")}},
+		{{Key: "$project", Value: commentViewProjection(viewerID)}},
+		{{Key: "$group", Value: bson.M{"_id": "$parent_id", "replies": bson.M{"$push": "$$ROOT"}}}},
+		{{Key: "$project", Value: bson.M{"replies": bson.M{"$slice": bson.A{"$replies", InlineReplyLimit}}}}},
+	}
+	cursor, err := db.CommentsCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var threads []struct {
+		ParentID primitive.ObjectID `bson:"_id"`
+		Replies  []*Comment         `bson:"replies"`
+	}
+	if err = cursor.All(ctx, &threads); err != nil {
+		return err
+	}
+
+	replies := make(map[primitive.ObjectID][]*Comment, len(threads))
+	for _, thread := range threads {
+		replies[thread.ParentID] = thread.Replies
+	}
+
+	for _, comment := range comments {
+		comment.Replies = replies[comment.ID]
+		if len(comment.Replies) == 0 || comment.ReplyCount <= len(comment.Replies) {
+			continue
+		}
+
+		last := comment.Replies[len(comment.Replies)-1]
+		cursor := Cursor{ID: bson.RawValue{Type: bsontype.ObjectID, Value: last.ID[:]}}
+		if comment.RepliesNextCursor, err = cursor.Encode(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindCommentsByUserID finds all comments written by a user, oldest first
+func FindCommentsByUserID(userID primitive.ObjectID, ctx context.Context) ([]*Comment, error) {
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: 1}})
+	cursor, err := db.CommentsCollection().Find(ctx, bson.M{"user_id": userID}, opts)
 	if err != nil {
 		return nil, err
 	}
-	var comments []*Comment
+	defer cursor.Close(ctx)
+
+	comments := []*Comment{}
 	if err = cursor.All(ctx, &comments); err != nil {
 		return nil, err
 	}
 	return comments, nil
 }
 
-// DeleteUserComment deletes a comment by ID and user ID
-func DeleteUserComment(id string, userId primitive.ObjectID, ctx context.Context) error {
+// DeleteUserComment deletes a comment by ID if it was written by the user or the user can delete any comment.
+// Comments with replies are kept without their content and author so the replies still make sense.
+func DeleteUserComment(id string, user *User, ctx context.Context) error {
 	// Encode the ID to an ObjectID type
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -69,21 +187,72 @@ func DeleteUserComment(id string, userId primitive.ObjectID, ctx context.Context
 	}
 
 	// Create the filter
-	filter := bson.D{{Key: "_id", Value: objectID}, {Key: "user._id", Value: userId}}
+	filter := bson.M{"_id": objectID, "user_id": user.ID}
+
+	if user.HasPermission(PermCommentsDeleteAny) {
+		filter = bson.M{"_id": objectID}
+	}
+
+	// Delete the comment if nobody replied to it
+	var comment Comment
+	withoutReplies := bson.M{"$and": bson.A{filter, bson.M{"reply_count": bson.M{"$not": bson.M{"$gt": 0}}}}}
+	err = db.CommentsCollection().FindOneAndDelete(ctx, withoutReplies).Decode(&comment)
+	if err == nil {
+		if comment.ParentID == nil {
+			return nil
+		}
 
-	// Delete the post by ID and user ID if they match
-	results, err := db.CommentsCollection().DeleteOne(ctx, filter)
+		return uncountReply(comment.ParentID, ctx)
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	// Otherwise only remove its content and author
+	update := bson.M{"$set": bson.M{"deleted": true, "content": "", "user_id": primitive.NilObjectID}}
+	results, err := db.CommentsCollection().UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
 
 	// Check if nothing was deleted and return an error
-	if results.DeletedCount == 0 {
+	if results.MatchedCount == 0 {
 		return mongo.ErrNoDocuments
 	}
 
-	return nil
+	// The last reply may have been deleted in the meantime
+	return removeEmptyTombstone(objectID, ctx)
+}
 
+// uncountReply uncounts a removed reply on the comment it replied to, removing that comment as well if it was deleted
+// and this was its last reply
+func uncountReply(parentID *primitive.ObjectID, ctx context.Context) error {
+	if parentID == nil {
+		return nil
+	}
+
+	_, err := db.CommentsCollection().UpdateOne(ctx, bson.M{"_id": parentID}, bson.M{"$inc": bson.M{"reply_count": -1}})
+	if err != nil {
+		return err
+	}
+
+	return removeEmptyTombstone(*parentID, ctx)
+}
+
+// removeEmptyTombstone removes a deleted comment that has no replies left, which only remained to keep its replies
+// in place
+func removeEmptyTombstone(id primitive.ObjectID, ctx context.Context) error {
+	var tombstone Comment
+	filter := bson.M{"_id": id, "deleted": true, "reply_count": bson.M{"$not": bson.M{"$gt": 0}}}
+	err := db.CommentsCollection().FindOneAndDelete(ctx, filter).Decode(&tombstone)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return uncountReply(tombstone.ParentID, ctx)
 }
 
 // UpdateUserComment updates a comment by ID and user ID
@@ -95,7 +264,7 @@ func UpdateUserComment(id, content string, userId primitive.ObjectID, ctx contex
 	}
 
 	// Create the filter and update
-	filter := bson.D{{Key: "_id", Value: objectID}, {Key: "user._id", Value: userId}}
+	filter := bson.D{{Key: "_id", Value: objectID}, {Key: "user_id", Value: userId}}
 	update := bson.D{{Key: "$set", Value: bson.D{{Key: "content", Value: content}}}}
 
 	// Set the return document to after
@@ -111,45 +280,30 @@ func UpdateUserComment(id, content string, userId primitive.ObjectID, ctx contex
 	return nil
 }
 
+// Save saves the comment. A reply is first counted on the comment it replies to, so a parent deleted at the same time
+// either keeps the reply or rejects it. It returns mongo.ErrNoDocuments if the parent no longer exists or was deleted.
 func (c *Comment) Save(ctx context.Context) error {
-	// Insert the post into the database
+	if c.ParentID != nil {
+		filter := bson.M{"_id": c.ParentID, "deleted": bson.M{"$ne": true}}
+		result, err := db.CommentsCollection().UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"reply_count": 1}})
+		if err != nil {
+			return err
+		}
+
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+	}
+
+	// Insert the comment into the database
 	_, err := db.CommentsCollection().InsertOne(ctx, c)
 	if err != nil {
+		// Take back the count of the reply that was not saved
+		if uncountErr := uncountReply(c.ParentID, ctx); uncountErr != nil {
+			return errors.Join(err, uncountErr)
+		}
 		return err
 	}
 
 	return nil
 }
-
-// AddLike adds a like to the comment
-func (c *Comment) AddLike() {
-	c.Likes++
-}
-
-// AddDislike adds a dislike to the comment
-func (c *Comment) AddDislike() {
-	c.Dislikes++
-}
-
-// ToggleLikeOnComment updates the like or dislike state of a comment for a given user
-func ToggleLikeOnComment(commentID string, userID string, like bool) error {
-
-	cID, _ := primitive.ObjectIDFromHex(commentID)
-	uID, _ := primitive.ObjectIDFromHex(userID)
-
-	update := bson.M{}
-	if like {
-		update = bson.M{
-			"$addToSet": bson.M{"liked_by": uID},
-			"$pull":     bson.M{"disliked_by": uID},
-		}
-	} else {
-		update = bson.M{
-			"$addToSet": bson.M{"disliked_by": uID},
-			"$pull":     bson.M{"liked_by": uID},
-		}
-	}
-
-	_, err := db.CommentsCollection().UpdateOne(context.Background(), bson.M{"_id": cID}, update)
-	return err
-}
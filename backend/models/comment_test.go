@@ -0,0 +1,33 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewReply(t *testing.T) {
+	parent := NewComment(primitive.NewObjectID(), defaultUser, "Top-level comment")
+
+	reply, err := NewReply(parent, defaultUser, "Reply")
+	assert.Nil(t, err)
+	assert.Equal(t, parent.PostID, reply.PostID)
+	assert.Equal(t, &parent.ID, reply.ParentID)
+	assert.Equal(t, 1, reply.Depth)
+
+	// Replies to the deepest comments are refused
+	parent.Depth = MaxCommentDepth
+	_, err = NewReply(parent, defaultUser, "Too deep")
+	assert.Equal(t, ErrCommentTooDeep, err)
+}
+
+func TestLoadRepliesWithoutReplies(t *testing.T) {
+	// Comments nobody replied to do not need a query
+	comments := []*Comment{NewComment(primitive.NewObjectID(), defaultUser, "Comment")}
+
+	assert.Nil(t, LoadReplies(comments, defaultUser.ID, context.TODO()))
+	assert.Nil(t, comments[0].Replies)
+	assert.Empty(t, comments[0].RepliesNextCursor)
+}
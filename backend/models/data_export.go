@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Statuses of a data export job
+const (
+	ExportStatusPending = "pending"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+)
+
+// DataExport is a background job building the archive of a user's personal data.
+type DataExport struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	UserID      primitive.ObjectID `json:"-" bson:"user_id"`
+	Status      string             `json:"status" bson:"status"`
+	FilePath    string             `json:"-" bson:"file_path,omitempty"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	ExpiresAt   time.Time          `json:"expires_at" bson:"expires_at"` // The archive is deleted after this
+}
+
+type DataExportStore struct {
+	Collection *mongo.Collection
+}
+
+// NewDataExport creates a pending export for the user whose archive is kept until ttl has passed
+func NewDataExport(userID primitive.ObjectID, ttl time.Duration) *DataExport {
+	now := time.Now()
+	return &DataExport{ID: primitive.NewObjectID(), UserID: userID, Status: ExportStatusPending, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+}
+
+// IsExpired checks if the export's archive is no longer available
+func (e *DataExport) IsExpired() bool {
+	return time.Now().After(e.ExpiresAt)
+}
+
+// IsStalled checks if the export is still pending after the time building it may take, which happens when the server
+// stopped while building it
+func (e *DataExport) IsStalled(buildTimeout time.Duration) bool {
+	return e.Status == ExportStatusPending && time.Since(e.CreatedAt) > buildTimeout
+}
+
+// EnsureIndexes creates the index used to find a user's latest export and the index used to clean up expired exports
+func (store *DataExportStore) EnsureIndexes(ctx context.Context) error {
+	_, err := store.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}},
+	})
+
+	return err
+}
+
+// Save saves an export to the database
+func (store *DataExportStore) Save(e *DataExport, ctx context.Context) error {
+	_, err := store.Collection.InsertOne(ctx, e)
+
+	return err
+}
+
+// FindExportByID finds an export by ID
+func (store *DataExportStore) FindExportByID(id primitive.ObjectID, ctx context.Context) (*DataExport, error) {
+	var export DataExport
+	err := store.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// FindLatestExport finds the user's most recent export
+func (store *DataExportStore) FindLatestExport(userID primitive.ObjectID, ctx context.Context) (*DataExport, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var export DataExport
+	err := store.Collection.FindOne(ctx, bson.M{"user_id": userID}, opts).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// CompleteExport records that the export finished, with the path of the archive if it succeeded
+func (store *DataExportStore) CompleteExport(id primitive.ObjectID, status, filePath string, ctx context.Context) error {
+	update := bson.M{"$set": bson.M{"status": status, "file_path": filePath, "completed_at": time.Now()}}
+	_, err := store.Collection.UpdateByID(ctx, id, update)
+
+	return err
+}
+
+// FindExpiredExports finds up to limit exports that expired before the given time
+func (store *DataExportStore) FindExpiredExports(before time.Time, limit int64, ctx context.Context) ([]*DataExport, error) {
+	cursor, err := store.Collection.Find(ctx, bson.M{"expires_at": bson.M{"$lte": before}}, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	exports := []*DataExport{}
+	if err = cursor.All(ctx, &exports); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// FindExportsByUserID finds all exports of a user
+func (store *DataExportStore) FindExportsByUserID(userID primitive.ObjectID, ctx context.Context) ([]*DataExport, error) {
+	cursor, err := store.Collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	exports := []*DataExport{}
+	if err = cursor.All(ctx, &exports); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// DeleteExport deletes an export from the database
+func (store *DataExportStore) DeleteExport(id primitive.ObjectID, ctx context.Context) error {
+	_, err := store.Collection.DeleteOne(ctx, bson.M{"_id": id})
+
+	return err
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Reasons recorded for failed logins
+const (
+	LoginFailureInvalidPassword      = "invalid_password"
+	LoginFailureInvalidTwoFactorCode = "invalid_two_factor_code"
+	LoginFailureLocked               = "locked"
+)
+
+// loginAttemptRetention is how long failed logins stay visible to the user
+const loginAttemptRetention = 90 * 24 * time.Hour
+
+// LockoutPolicy decides when repeated failed logins lock an account or client IP and for how long.
+type LockoutPolicy struct {
+	MaxAttempts  int           // Failures allowed before the first lock
+	BaseDuration time.Duration // Length of the first lock, doubled with every failure after it
+	MaxDuration  time.Duration
+	Window       time.Duration // Failures are forgotten after this long without another one
+}
+
+// LockDuration returns how long to lock after the given number of consecutive failures, zero if not at all
+func (p LockoutPolicy) LockDuration(failures int) time.Duration {
+	if failures < p.MaxAttempts {
+		return 0
+	}
+
+	duration := p.BaseDuration
+	for i := p.MaxAttempts; i < failures; i++ {
+		duration *= 2
+		if duration >= p.MaxDuration {
+			return p.MaxDuration
+		}
+	}
+
+	return min(duration, p.MaxDuration)
+}
+
+// LoginLock counts the consecutive failed logins of an account or client IP.
+type LoginLock struct {
+	Key         string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	LockedUntil time.Time `bson:"locked_until,omitempty"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+type LoginLockStore struct {
+	Collection *mongo.Collection
+}
+
+// AccountLockKey returns the lock key of the account with the given email
+func AccountLockKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPLockKey returns the lock key of a client IP
+func IPLockKey(ip string) string {
+	return "ip:" + ip
+}
+
+// EnsureIndexes creates the TTL index that removes the failures once their window has passed
+func (store *LoginLockStore) EnsureIndexes(ctx context.Context) error {
+	_, err := store.Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	return err
+}
+
+// LockedUntil returns when the lock of the key ends, or the zero time if it is not locked
+func (store *LoginLockStore) LockedUntil(key string, ctx context.Context) (time.Time, error) {
+	var lock LoginLock
+
+	// MongoDB only removes expired documents periodically, so filter them out here as well
+	filter := bson.M{"_id": key, "locked_until": bson.M{"$gt": time.Now()}}
+	err := store.Collection.FindOne(ctx, filter).Decode(&lock)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return lock.LockedUntil, nil
+}
+
+// RecordFailure counts a failed login for the key and locks it when the policy says so
+func (store *LoginLockStore) RecordFailure(key string, policy LockoutPolicy, ctx context.Context) (*LoginLock, error) {
+	now := time.Now()
+
+	// Increment atomically so concurrent attempts cannot slip past the limit
+	update := bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"expires_at": now.Add(policy.Window)}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var lock LoginLock
+	err := store.Collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&lock)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := policy.LockDuration(lock.Failures)
+	if duration == 0 {
+		return &lock, nil
+	}
+
+	// Keep the failures around for a window after the lock ends so the next lock is longer
+	lock.LockedUntil = now.Add(duration)
+	lock.ExpiresAt = lock.LockedUntil.Add(policy.Window)
+	update = bson.M{"$set": bson.M{"locked_until": lock.LockedUntil, "expires_at": lock.ExpiresAt}}
+	if _, err := store.Collection.UpdateOne(ctx, bson.M{"_id": key}, update); err != nil {
+		return nil, err
+	}
+
+	return &lock, nil
+}
+
+// Reset clears the failures and any lock of the key
+func (store *LoginLockStore) Reset(key string, ctx context.Context) error {
+	_, err := store.Collection.DeleteOne(ctx, bson.M{"_id": key})
+
+	return err
+}
+
+// LoginAttempt is a failed login of an account, kept so its owner can see it.
+type LoginAttempt struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"-" bson:"user_id"`
+	IP        string             `json:"ip" bson:"ip"`
+	Device    string             `json:"device" bson:"device"` // User agent of the client
+	Reason    string             `json:"reason" bson:"reason"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type LoginAttemptStore struct {
+	Collection *mongo.Collection
+}
+
+// NewLoginAttempt creates a failed login record for the user
+func NewLoginAttempt(userID primitive.ObjectID, ip, device, reason string) *LoginAttempt {
+	return &LoginAttempt{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		IP:        ip,
+		Device:    device,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+}
+
+// EnsureIndexes creates the index used to list a user's attempts and the TTL index that removes old ones
+func (store *LoginAttemptStore) EnsureIndexes(ctx context.Context) error {
+	_, err := store.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(loginAttemptRetention.Seconds()))},
+	})
+
+	return err
+}
+
+// Save saves a login attempt to the database
+func (store *LoginAttemptStore) Save(a *LoginAttempt, ctx context.Context) error {
+	_, err := store.Collection.InsertOne(ctx, a)
+
+	return err
+}
+
+// FindAttemptsByUserID finds the latest failed logins of a user, newest first
+func (store *LoginAttemptStore) FindAttemptsByUserID(userID primitive.ObjectID, limit int64, ctx context.Context) ([]*LoginAttempt, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := store.Collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	attempts := []*LoginAttempt{}
+	if err = cursor.All(ctx, &attempts); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
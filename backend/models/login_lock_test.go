@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var testLockoutPolicy = LockoutPolicy{
+	MaxAttempts:  3,
+	BaseDuration: time.Minute,
+	MaxDuration:  10 * time.Minute,
+	Window:       time.Hour,
+}
+
+func TestLockDuration(t *testing.T) {
+	assert.Equal(t, time.Duration(0), testLockoutPolicy.LockDuration(2))
+	assert.Equal(t, time.Minute, testLockoutPolicy.LockDuration(3))
+	assert.Equal(t, 2*time.Minute, testLockoutPolicy.LockDuration(4))
+	assert.Equal(t, 8*time.Minute, testLockoutPolicy.LockDuration(6))
+
+	// The lock never grows past the maximum
+	assert.Equal(t, 10*time.Minute, testLockoutPolicy.LockDuration(7))
+	assert.Equal(t, 10*time.Minute, testLockoutPolicy.LockDuration(100))
+}
+
+func TestAccountLockKey(t *testing.T) {
+	assert.Equal(t, AccountLockKey("user@example.com"), AccountLockKey(" User@Example.com "))
+	assert.NotEqual(t, AccountLockKey("127.0.0.1"), IPLockKey("127.0.0.1"))
+}
+
+func TestRecordFailure(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("below the limit", func(mt *mtest.T) {
+		// Mock the counter returned from the FindOneAndUpdate() function
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: "account:user@example.com"},
+			{Key: "failures", Value: 2},
+		}}))
+
+		store := &LoginLockStore{Collection: mt.Coll}
+		lock, err := store.RecordFailure("account:user@example.com", testLockoutPolicy, context.TODO())
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, lock.Failures)
+		assert.True(t, lock.LockedUntil.IsZero())
+	})
+
+	mt.Run("reaching the limit", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: "account:user@example.com"},
+				{Key: "failures", Value: 4},
+			}}),
+			// Mock the result of setting the lock with UpdateOne()
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		store := &LoginLockStore{Collection: mt.Coll}
+		lock, err := store.RecordFailure("account:user@example.com", testLockoutPolicy, context.TODO())
+
+		// Assert the lock doubled once past the limit
+		assert.Nil(t, err)
+		assert.WithinDuration(t, time.Now().Add(2*time.Minute), lock.LockedUntil, time.Second)
+		assert.WithinDuration(t, lock.LockedUntil.Add(time.Hour), lock.ExpiresAt, time.Second)
+	})
+}
+
+func TestLockedUntil(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("not locked", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "infy.login_locks", mtest.FirstBatch))
+
+		store := &LoginLockStore{Collection: mt.Coll}
+		lockedUntil, err := store.LockedUntil("ip:127.0.0.1", context.TODO())
+
+		assert.Nil(t, err)
+		assert.True(t, lockedUntil.IsZero())
+	})
+}
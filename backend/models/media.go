@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Media types of the movies and TV shows referenced by posts, polls and watch lists
+const (
+	MediaTypeMovie = "movie"
+	MediaTypeTV    = "tv"
+)
+
+// IsValidMediaType checks if the media type is one of the supported media types
+func IsValidMediaType(mediaType string) bool {
+	return mediaType == MediaTypeMovie || mediaType == MediaTypeTV
+}
+
+// MediaKey builds the key stored in watch lists for a movie or TV show.
+// Movies are stored as their bare TMDB ID so existing lists stay valid, TV shows are prefixed with "tv:".
+func MediaKey(mediaType, mediaID string) string {
+	if mediaType == MediaTypeTV {
+		return MediaTypeTV + ":" + mediaID
+	}
+
+	return mediaID
+}
+
+// ParseMediaKey splits a watch list key into its media type and TMDB ID
+func ParseMediaKey(key string) (string, string) {
+	if id, found := strings.CutPrefix(key, MediaTypeTV+":"); found {
+		return MediaTypeTV, id
+	}
+
+	return MediaTypeMovie, key
+}
+
+// mediaTypeFilter matches documents of the given media type, treating documents saved before
+// TV support without a media type as movies
+func mediaTypeFilter(mediaType string) interface{} {
+	if mediaType == MediaTypeMovie {
+		return bson.M{"$in": bson.A{MediaTypeMovie, nil}}
+	}
+
+	return mediaType
+}
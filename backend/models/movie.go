@@ -0,0 +1,102 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MovieRecord is the normalized copy of a TMDB movie kept in the local catalog.
+type MovieRecord struct {
+	ID           int          `json:"id" bson:"_id"`
+	Title        string       `json:"title" bson:"title"`
+	Year         int          `json:"year" bson:"year"`
+	ReleaseDate  string       `json:"release_date" bson:"release_date"`
+	Tagline      string       `json:"tagline" bson:"tagline"`
+	Overview     string       `json:"overview" bson:"overview"`
+	Genres       []Genre      `json:"genres" bson:"genres"`
+	Runtime      int          `json:"runtime" bson:"runtime"`
+	Rating       float64      `json:"rating" bson:"rating"`
+	PosterPath   string       `json:"poster_path" bson:"poster_path"`
+	BackdropPath string       `json:"backdrop_path" bson:"backdrop_path"`
+	Cast         []CastMember `json:"cast" bson:"cast"`
+	UpdatedAt    time.Time    `json:"updated_at" bson:"updated_at"`
+}
+
+type Genre struct {
+	ID   int    `json:"id" bson:"id"`
+	Name string `json:"name" bson:"name"`
+}
+
+type CastMember struct {
+	ID          int    `json:"id" bson:"id"`
+	Name        string `json:"name" bson:"name"`
+	Character   string `json:"character" bson:"character"`
+	ProfilePath string `json:"profile_path" bson:"profile_path"`
+}
+
+type MovieStore struct {
+	Collection *mongo.Collection
+}
+
+// EnsureIndexes creates the index used to find stale records
+func (store *MovieStore) EnsureIndexes(ctx context.Context) error {
+	_, err := store.Collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "updated_at", Value: 1}}})
+
+	return err
+}
+
+// UpsertMovie inserts the movie record or replaces the existing record with the same ID
+func (store *MovieStore) UpsertMovie(ctx context.Context, movie *MovieRecord) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := store.Collection.ReplaceOne(ctx, bson.M{"_id": movie.ID}, movie, opts)
+
+	return err
+}
+
+// FindMoviesByIDs finds the movie records with the given IDs, skipping IDs that are not in the catalog
+func (store *MovieStore) FindMoviesByIDs(ctx context.Context, ids []int) ([]*MovieRecord, error) {
+	cursor, err := store.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var movies []*MovieRecord
+	if err = cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
+// FindStaleMovieIDs finds the IDs of the records last updated before the given time, oldest first
+func (store *MovieStore) FindStaleMovieIDs(ctx context.Context, updatedBefore time.Time, limit int64) ([]int, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: 1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := store.Collection.Find(ctx, bson.M{"updated_at": bson.M{"$lt": updatedBefore}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []struct {
+		ID int `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Limits of the number of documents in a page
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// ErrInvalidCursor is returned for cursors that were not returned by a list, or by a list ordered differently
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of the last document of a page, the next page starts after it
+type Cursor struct {
+	ID    bson.RawValue // _id of the last document
+	Score bson.RawValue // Value of the ranking field of the last document, empty for lists ordered by _id only
+}
+
+// Page selects the documents of a list to return
+type Page struct {
+	Limit int64
+	After *Cursor // Nil for the first page
+}
+
+// NewPage creates a page of up to limit documents, after the document the cursor points to if it is not empty
+func NewPage(limit int64, cursor string) (Page, error) {
+	page := Page{Limit: limit}
+	if cursor == "" {
+		return page, nil
+	}
+
+	after, err := DecodeCursor(cursor)
+	if err != nil {
+		return page, err
+	}
+	page.After = after
+
+	return page, nil
+}
+
+// limit returns the number of documents to return, within the page limits
+func (p Page) limit() int64 {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+
+	return min(p.Limit, MaxPageLimit)
+}
+
+// Encode returns the opaque string clients pass back to get the next page
+func (c *Cursor) Encode() (string, error) {
+	doc := bson.D{{Key: "i", Value: c.ID}}
+	if c.Score.Type != 0 {
+		doc = append(doc, bson.E{Key: "s", Value: c.Score})
+	}
+
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor parses a cursor returned by Encode. Only ID and score types that can be stored in a cursor are
+// accepted, so a crafted cursor cannot smuggle query operators into the filter.
+func DecodeCursor(cursor string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	raw := bson.Raw(b)
+	if err := raw.Validate(); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	c.ID, err = raw.LookupErr("i")
+	if err != nil || (c.ID.Type != bsontype.ObjectID && c.ID.Type != bsontype.String) {
+		return nil, ErrInvalidCursor
+	}
+
+	if score, err := raw.LookupErr("s"); err == nil {
+		if score.Type != bsontype.Int32 && score.Type != bsontype.Int64 && score.Type != bsontype.Double {
+			return nil, ErrInvalidCursor
+		}
+		c.Score = score
+	}
+
+	return &c, nil
+}
+
+// filter matches the documents after the cursor in a list sorted descending by scoreField and then _id
+func (c *Cursor) filter(scoreField string) (bson.M, error) {
+	if scoreField == "" {
+		if c.Score.Type != 0 {
+			return nil, ErrInvalidCursor
+		}
+
+		return bson.M{"_id": bson.M{"$lt": c.ID}}, nil
+	}
+
+	if c.Score.Type == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return bson.M{"$or": []bson.M{
+		{scoreField: bson.M{"$lt": c.Score}},
+		{scoreField: c.Score, "_id": bson.M{"$lt": c.ID}},
+	}}, nil
+}
+
+// pageFilter adds the position of the page to the filter of a list sorted by pageSort
+func pageFilter(filter bson.M, scoreField string, page Page) (bson.M, error) {
+	if page.After == nil {
+		return filter, nil
+	}
+
+	after, err := page.After.filter(scoreField)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{"$and": []bson.M{filter, after}}, nil
+}
+
+// pageSort sorts a list newest first or, if scoreField is not empty, highest score first
+func pageSort(scoreField string) bson.D {
+	if scoreField != "" {
+		return bson.D{{Key: scoreField, Value: -1}, {Key: "_id", Value: -1}}
+	}
+
+	return bson.D{{Key: "_id", Value: -1}}
+}
+
+// findPage finds a page of the documents matching the filter, newest first or, if scoreField is not empty,
+// highest score first. Only the fields in the projection are returned, unless it is nil. It also returns the
+// cursor of the next page, which is empty on the last page.
+func findPage[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, scoreField string, projection bson.M, page Page) ([]*T, string, error) {
+	filter, err := pageFilter(filter, scoreField, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// One more document than needed tells whether there is a next page
+	limit := page.limit()
+	opts := options.Find().SetSort(pageSort(scoreField)).SetLimit(limit + 1)
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.Raw
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, "", err
+	}
+
+	return decodePage[T](docs, scoreField, limit)
+}
+
+// aggregatePage finds a page of the documents returned by the pipeline, ordered by their _id descending, for lists
+// of documents that are not stored in a collection of their own.
+func aggregatePage[T any](ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline, page Page) ([]*T, string, error) {
+	filter, err := pageFilter(bson.M{}, "", page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limit := page.limit()
+	pipeline = append(pipeline,
+		bson.D{{Key: "$match", Value: filter}},
+		bson.D{{Key: "$sort", Value: pageSort("")}},
+		bson.D{{Key: "$limit", Value: limit + 1}},
+	)
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.Raw
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, "", err
+	}
+
+	return decodePage[T](docs, "", limit)
+}
+
+// decodePage decodes up to limit documents of a page and returns the cursor of the next page if there were more
+func decodePage[T any](docs []bson.Raw, scoreField string, limit int64) ([]*T, string, error) {
+	var next string
+	if int64(len(docs)) > limit {
+		docs = docs[:limit]
+
+		last := docs[len(docs)-1]
+		after := Cursor{ID: last.Lookup("_id")}
+		if scoreField != "" {
+			after.Score = last.Lookup(scoreField)
+		}
+
+		var err error
+		if next, err = after.Encode(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	items := make([]*T, 0, len(docs))
+	for _, doc := range docs {
+		var item T
+		if err := bson.Unmarshal(doc, &item); err != nil {
+			return nil, "", err
+		}
+		items = append(items, &item)
+	}
+
+	return items, next, nil
+}
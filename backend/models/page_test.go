@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func rawValue(t *testing.T, value interface{}) bson.RawValue {
+	raw, err := bson.Marshal(bson.M{"v": value})
+	assert.Nil(t, err)
+
+	return bson.Raw(raw).Lookup("v")
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	cursor := Cursor{ID: rawValue(t, id), Score: rawValue(t, int32(7))}
+
+	encoded, err := cursor.Encode()
+	assert.Nil(t, err)
+
+	page, err := NewPage(10, encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, id, page.After.ID.ObjectID())
+	assert.Equal(t, int32(7), page.After.Score.Int32())
+
+	// Polls use hex strings as IDs
+	cursor = Cursor{ID: rawValue(t, id.Hex())}
+	encoded, err = cursor.Encode()
+	assert.Nil(t, err)
+
+	decoded, err := DecodeCursor(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, id.Hex(), decoded.ID.StringValue())
+	assert.Zero(t, decoded.Score.Type)
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	_, err := DecodeCursor("not a cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Documents could turn into query operators, so they are rejected
+	cursor := Cursor{ID: rawValue(t, bson.M{"$gt": ""})}
+	encoded, err := cursor.Encode()
+	assert.Nil(t, err)
+
+	_, err = DecodeCursor(encoded)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestCursorFilter(t *testing.T) {
+	id := rawValue(t, primitive.NewObjectID())
+
+	filter, err := (&Cursor{ID: id}).filter("")
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{"_id": bson.M{"$lt": id}}, filter)
+
+	// A cursor of a list ordered by _id cannot be used for a ranked list and the other way around
+	_, err = (&Cursor{ID: id}).filter("report_count")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = (&Cursor{ID: id, Score: rawValue(t, int32(1))}).filter("")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestPageLimit(t *testing.T) {
+	assert.Equal(t, int64(DefaultPageLimit), Page{}.limit())
+	assert.Equal(t, int64(MaxPageLimit), Page{Limit: MaxPageLimit + 1}.limit())
+	assert.Equal(t, int64(5), Page{Limit: 5}.limit())
+}
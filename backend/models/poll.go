@@ -2,12 +2,12 @@ package models
 
 import (
 	"context"
-	"fmt"
 	"infy/db"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -15,6 +15,7 @@ import (
 type Poll struct {
 	ID        string    `bson:"_id" json:"id"`
 	MovieID   string    `bson:"movie_id" json:"movie_id"`
+	MediaType string    `bson:"media_type,omitempty" json:"media_type"`
 	Question  string    `bson:"question" json:"question"`
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	EndsAt    time.Time `bson:"ends_at" json:"ends_at"`
@@ -28,11 +29,21 @@ type Option struct {
 	Votes int    `bson:"votes" json:"votes"`
 }
 
-// NewPoll creates a new Poll with the specified question and associated movie ID.
-func NewPoll(question, movieID string) *Poll {
+// PollVote records which option a user voted for, so users can find their votes in their data export.
+type PollVote struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	PollID    string             `bson:"poll_id" json:"poll_id"`
+	OptionID  string             `bson:"option_id" json:"option_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"-"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// NewPoll creates a new Poll with the specified question and associated movie or TV show ID.
+func NewPoll(question, mediaType, movieID string) *Poll {
 	return &Poll{
 		ID:        primitive.NewObjectID().Hex(),
 		MovieID:   movieID,
+		MediaType: mediaType,
 		Question:  question,
 		CreatedAt: time.Now(),
 		EndsAt:    time.Now().Add(24 * time.Hour), // Poll ends in 24 hours from creation
@@ -57,12 +68,8 @@ func (p *Poll) Save(ctx context.Context) error {
 
 // IncrementPollOptionVote increases the vote count for a specific option in a poll.
 func IncrementPollOptionVote(pollID, optionID string, ctx context.Context) error {
-	pollObjectID, err := primitive.ObjectIDFromHex(pollID)
-	if err != nil {
-		return err // Handle invalid ObjectID format
-	}
-
-	filter := bson.M{"_id": pollObjectID}
+	// Poll IDs are stored as hex strings, see NewPoll
+	filter := bson.M{"_id": pollID}
 	update := bson.M{"$inc": bson.M{"options.$[elem].votes": 1}}
 	arrayFilters := options.Update().SetArrayFilters(options.ArrayFilters{
 		Filters: []interface{}{bson.M{"elem._id": optionID}},
@@ -73,27 +80,41 @@ func IncrementPollOptionVote(pollID, optionID string, ctx context.Context) error
 		return err
 	}
 
-	fmt.Println("MatchedCount: ", result.MatchedCount)
-	fmt.Println("ModifiedCount: ", result.ModifiedCount)
-	fmt.Println("UpsertedCount: ", result.UpsertedCount)
-	fmt.Println("UpsertedID: ", result.UpsertedID)
+	if result.ModifiedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 
 	return nil
 }
 
-// FindPollsByMovieID retrieves all polls associated with a specific movie ID.
-func FindPollsByMovieID(movieID string, ctx context.Context) ([]*Poll, error) {
-	var polls []*Poll
-	filter := bson.M{"movie_id": movieID}
-	cursor, err := db.PollsCollection().Find(ctx, filter)
+// RecordPollVote saves which option of a poll the user voted for
+func RecordPollVote(pollID, optionID string, userID primitive.ObjectID, ctx context.Context) error {
+	vote := PollVote{ID: primitive.NewObjectID(), PollID: pollID, OptionID: optionID, UserID: userID, CreatedAt: time.Now()}
+	_, err := db.PollVotesCollection().InsertOne(ctx, vote)
+
+	return err
+}
+
+// FindPollVotesByUserID retrieves all poll votes of a user, oldest first
+func FindPollVotesByUserID(userID primitive.ObjectID, ctx context.Context) ([]*PollVote, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := db.PollVotesCollection().Find(ctx, bson.M{"user_id": userID}, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	if err = cursor.All(ctx, &polls); err != nil {
+	votes := []*PollVote{}
+	if err = cursor.All(ctx, &votes); err != nil {
 		return nil, err
 	}
 
-	return polls, nil
+	return votes, nil
+}
+
+// FindPollsByMedia retrieves a page of the polls associated with a specific movie or TV show, newest first.
+func FindPollsByMedia(mediaType, movieID string, ctx context.Context, page Page) ([]*Poll, string, error) {
+	filter := bson.M{"movie_id": movieID, "media_type": mediaTypeFilter(mediaType)}
+
+	return findPage[Poll](ctx, db.PollsCollection(), filter, "", nil, page)
 }
@@ -11,11 +11,14 @@ import (
 )
 
 type Post struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id"`
-	User      *User              `json:"user" bson:"user"`
-	Reactions []UserReactions    `json:"-"`
-	Movie     *Movie             `json:"movie"`
-	Content   string             `json:"content"`
+	ID             primitive.ObjectID `json:"id" bson:"_id"`
+	UserID         primitive.ObjectID `json:"-" bson:"user_id"`
+	User           *Author            `json:"user" bson:"-"` // Set by AuthorLoader when reading
+	Reactions      []Reaction         `json:"-" bson:"reactions"`
+	ReactionCounts map[string]int     `json:"reaction_counts" bson:"reaction_counts"` // Kept in sync with Reactions by ReactionStore
+	ViewerReaction string             `json:"-" bson:"viewer_reaction,omitempty"`     // The reaction type of the user reading the post, instead of Reactions
+	Movie          *Movie             `json:"movie"`
+	Content        string             `json:"content"`
 }
 
 type ReportedPost struct {
@@ -24,17 +27,13 @@ type ReportedPost struct {
 	Post        *Post              `json:"post" bson:"post"`
 }
 
-type UserReactions struct {
-	UserID   primitive.ObjectID `json:"user_id" bson:"user_id"`
-	Liked    bool               `json:"liked"`
-	Disliked bool               `json:"disliked"`
-}
-
+// Movie is the snapshot of the movie or TV show a post is about
 type Movie struct {
 	ID         int    `json:"id"`
 	Title      string `json:"title"`
 	PosterPath string `json:"poster_path"`
 	Tagline    string `json:"tagline"`
+	MediaType  string `json:"media_type" bson:"media_type,omitempty"`
 }
 
 type PostStore struct {
@@ -43,27 +42,27 @@ type PostStore struct {
 
 // NewPost creates a new post instance
 func NewPost(user *User, movie *Movie, content string) *Post {
-	return &Post{ID: primitive.NewObjectID(), User: user, Reactions: nil, Movie: movie, Content: content}
+	return &Post{ID: primitive.NewObjectID(), UserID: user.ID, User: user.Author(), Reactions: nil, ReactionCounts: map[string]int{}, Movie: movie, Content: content}
 }
 
-// FindAllPosts finds all the posts
-func (store *PostStore) FindAllPosts(ctx context.Context, limit int64) ([]*Post, error) {
-	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: -1}}).SetLimit(limit) // Corrected line for sorting
-	cursor, err := store.Collection.Find(ctx, bson.D{}, opts)
-	if err != nil {
-		return nil, err
+// postViewProjection reads a post with the viewer's reaction in place of the reactions of all users
+func postViewProjection(viewerID primitive.ObjectID) bson.M {
+	return bson.M{
+		"user_id":         1,
+		"reaction_counts": 1,
+		"movie":           1,
+		"content":         1,
+		"viewer_reaction": viewerReaction(viewerID),
 	}
-	defer cursor.Close(ctx)
+}
 
-	var posts []*Post
-	if err = cursor.All(ctx, &posts); err != nil {
-		return nil, err
-	}
-	return posts, nil
+// FindAllPosts finds a page of all the posts, newest first, with the reaction of the viewer
+func (store *PostStore) FindAllPosts(viewerID primitive.ObjectID, ctx context.Context, page Page) ([]*Post, string, error) {
+	return findPage[Post](ctx, store.Collection, bson.M{}, "", postViewProjection(viewerID), page)
 }
 
-// FindPostByID finds a post by ID
-func (store *PostStore) FindPostByID(id string, ctx context.Context) (*Post, error) {
+// FindPostByID finds a post by ID with the reaction of the viewer
+func (store *PostStore) FindPostByID(id string, viewerID primitive.ObjectID, ctx context.Context) (*Post, error) {
 	var post Post
 
 	// Encode the ID to an ObjectID type
@@ -73,7 +72,8 @@ func (store *PostStore) FindPostByID(id string, ctx context.Context) (*Post, err
 	}
 
 	// Find the post by ID
-	err = store.Collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&post)
+	opts := options.FindOne().SetProjection(postViewProjection(viewerID))
+	err = store.Collection.FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&post)
 
 	return &post, err
 }
@@ -87,7 +87,7 @@ func (store *PostStore) UpdateUserPost(id, content string, userId primitive.Obje
 	}
 
 	// Create the filter and update
-	filter := bson.D{{Key: "_id", Value: objectID}, {Key: "user._id", Value: userId}}
+	filter := bson.D{{Key: "_id", Value: objectID}, {Key: "user_id", Value: userId}}
 	update := bson.D{{Key: "$set", Value: bson.D{{Key: "content", Value: content}}}}
 
 	// Set the return document to after
@@ -112,9 +112,10 @@ func (store *PostStore) DeleteUserPost(id string, user *User, ctx context.Contex
 	}
 
 	// Create the filter
-	filter := bson.D{{Key: "_id", Value: postID}, {Key: "user._id", Value: user.ID}}
+	filter := bson.D{{Key: "_id", Value: postID}, {Key: "user_id", Value: user.ID}}
 
-	if user.IsAdmin {
+	// Moderators can delete any post
+	if user.HasPermission(PermPostsDeleteAny) {
 		filter = bson.D{{Key: "_id", Value: postID}}
 	}
 
@@ -143,89 +144,26 @@ func (store *PostStore) Save(ctx context.Context, p *Post) error {
 	return nil
 }
 
-func (store *PostStore) UpdateReaction(postID string, userID primitive.ObjectID, like, dislike bool, ctx context.Context) error {
-	// Encode the post ID to an ObjectID type
-	postObjectID, err := primitive.ObjectIDFromHex(postID)
-	if err != nil {
-		return err
-	}
-
-	// Create a UserReactions object
-	reaction := UserReactions{UserID: userID, Liked: like, Disliked: dislike}
-
-	// Remove the existing reaction from the user
-	_, err = store.Collection.UpdateOne(ctx, bson.M{"_id": postObjectID}, bson.M{"$pull": bson.M{"reactions": bson.M{"user_id": userID}}})
-	if err != nil {
-		return err
-	}
-
-	// Add the new reaction from the user
-	_, err = store.Collection.UpdateOne(ctx, bson.M{"_id": postObjectID}, bson.M{"$push": bson.M{"reactions": reaction}})
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (store *PostStore) FindPostsByUserID(userID string, ctx context.Context, limit int64) ([]*Post, error) {
+// FindPostsByUserID finds a page of the posts written by a user, newest first, with the reaction of the viewer
+func (store *PostStore) FindPostsByUserID(userID string, viewerID primitive.ObjectID, ctx context.Context, page Page) ([]*Post, string, error) {
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, err
-	}
-
-	// Define sorting and limiting options
-	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
-
-	filter := bson.M{"user._id": userObjectID}
-	cursor, err := store.Collection.Find(ctx, filter, opts)
-	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer cursor.Close(ctx)
 
-	var posts []*Post
-	if err = cursor.All(ctx, &posts); err != nil {
-		return nil, err
-	}
-
-	return posts, nil
+	return findPage[Post](ctx, store.Collection, bson.M{"user_id": userObjectID}, "", postViewProjection(viewerID), page)
 }
 
-func (store *PostStore) FindPostsByMovieID(movieID string, ctx context.Context, limit int64) ([]*Post, error) {
-	var posts []*Post
-
-	// Define sorting and limiting options
-	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
-
-	filter := bson.M{"movie_id": movieID}
-	cursor, err := store.Collection.Find(ctx, filter, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	if err = cursor.All(ctx, &posts); err != nil {
-		return nil, err
-	}
+// FindPostsByMedia finds a page of the posts about a movie or TV show, newest first, with the reaction of the viewer
+func (store *PostStore) FindPostsByMedia(mediaType string, mediaID int, viewerID primitive.ObjectID, ctx context.Context, page Page) ([]*Post, string, error) {
+	filter := bson.M{"movie.id": mediaID, "movie.media_type": mediaTypeFilter(mediaType)}
 
-	return posts, nil
+	return findPage[Post](ctx, store.Collection, filter, "", postViewProjection(viewerID), page)
 }
 
-func FindReportedPosts(ctx context.Context, limit int64) ([]*ReportedPost, error) {
-	opts := options.Find().SetSort(bson.D{{Key: "report_count", Value: -1}}).SetLimit(limit)
-	cursor, err := db.ReportedPostsCollection().Find(ctx, bson.D{}, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var reportedPosts []*ReportedPost
-	if err = cursor.All(ctx, &reportedPosts); err != nil {
-		return nil, err
-	}
-
-	return reportedPosts, nil
+// FindReportedPosts finds a page of the reported posts, most reported first
+func FindReportedPosts(ctx context.Context, page Page) ([]*ReportedPost, string, error) {
+	return findPage[ReportedPost](ctx, db.ReportedPostsCollection(), bson.M{}, "report_count", nil, page)
 }
 
 func ReportPost(postID string, ctx context.Context) error {
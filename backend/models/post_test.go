@@ -16,7 +16,7 @@ var (
 	expectedPosts = []*Post{
 		{
 			ID:        primitive.NewObjectID(),
-			User:      defaultUser,
+			UserID:    defaultUser.ID,
 			Reactions: nil,
 			Movie: &Movie{
 				ID:         1,
@@ -28,7 +28,7 @@ var (
 		},
 		{
 			ID:        primitive.NewObjectID(),
-			User:      defaultUser,
+			UserID:    defaultUser.ID,
 			Reactions: nil,
 			Movie: &Movie{
 				ID:         1,
@@ -40,7 +40,7 @@ var (
 		},
 		{
 			ID:        primitive.NewObjectID(),
-			User:      defaultUser,
+			UserID:    defaultUser.ID,
 			Reactions: nil,
 			Movie: &Movie{
 				ID:         1,
@@ -62,7 +62,8 @@ func TestNewPost(t *testing.T) {
 
 	assert.NotNil(t, post)
 	assert.NotEmpty(t, post.ID)
-	assert.Equal(t, user, post.User)
+	assert.Equal(t, user.ID, post.UserID)
+	assert.Equal(t, user.Author(), post.User)
 	assert.Nil(t, post.Reactions)
 	assert.Equal(t, movie, post.Movie)
 	assert.Equal(t, content, post.Content)
@@ -76,19 +77,19 @@ func TestFindAllPosts(t *testing.T) {
 		ns := mt.DB.Name() + "." + mt.Coll.Name()
 		first := mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, bson.D{
 			{Key: "_id", Value: expectedPosts[0].ID},
-			{Key: "user", Value: defaultUser},
+			{Key: "user_id", Value: defaultUser.ID},
 			{Key: "movie", Value: bson.D{{Key: "id", Value: expectedPosts[0].Movie.ID}, {Key: "title", Value: expectedPosts[0].Movie.Title}, {Key: "poster_path", Value: expectedPosts[0].Movie.PosterPath}, {Key: "tagline", Value: expectedPosts[0].Movie.Tagline}}},
 			{Key: "content", Value: expectedPosts[0].Content},
 		})
 		second := mtest.CreateCursorResponse(1, ns, mtest.NextBatch, bson.D{
 			{Key: "_id", Value: expectedPosts[1].ID},
-			{Key: "user", Value: defaultUser},
+			{Key: "user_id", Value: defaultUser.ID},
 			{Key: "movie", Value: bson.D{{Key: "id", Value: expectedPosts[1].Movie.ID}, {Key: "title", Value: expectedPosts[1].Movie.Title}, {Key: "poster_path", Value: expectedPosts[1].Movie.PosterPath}, {Key: "tagline", Value: expectedPosts[1].Movie.Tagline}}},
 			{Key: "content", Value: expectedPosts[1].Content},
 		})
 		third := mtest.CreateCursorResponse(0, ns, mtest.NextBatch, bson.D{
 			{Key: "_id", Value: expectedPosts[2].ID},
-			{Key: "user", Value: defaultUser},
+			{Key: "user_id", Value: defaultUser.ID},
 			{Key: "movie", Value: bson.D{{Key: "id", Value: expectedPosts[2].Movie.ID}, {Key: "title", Value: expectedPosts[2].Movie.Title}, {Key: "poster_path", Value: expectedPosts[2].Movie.PosterPath}, {Key: "tagline", Value: expectedPosts[2].Movie.Tagline}}},
 			{Key: "content", Value: expectedPosts[2].Content},
 		})
@@ -98,13 +99,35 @@ func TestFindAllPosts(t *testing.T) {
 		store := &PostStore{Collection: mt.Coll}
 
 		// Call the function that we are testing
-		posts, err := store.FindAllPosts(context.TODO(), 3)
+		posts, next, err := store.FindAllPosts(primitive.NilObjectID, context.TODO(), Page{Limit: 3})
 
 		// Assert the function did not return an error
 		assert.Nil(t, err)
 
 		// Assert the function returned the expected posts
 		assert.Equal(t, expectedPosts, posts)
+
+		// Assert there is no next page
+		assert.Empty(t, next)
+	})
+
+	mt.Run("find first page of posts", func(mt *mtest.T) {
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		batch := []bson.D{}
+		for _, post := range expectedPosts {
+			batch = append(batch, bson.D{{Key: "_id", Value: post.ID}, {Key: "user_id", Value: defaultUser.ID}, {Key: "movie", Value: post.Movie}, {Key: "content", Value: post.Content}})
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, batch...))
+
+		store := &PostStore{Collection: mt.Coll}
+		posts, next, err := store.FindAllPosts(primitive.NilObjectID, context.TODO(), Page{Limit: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, expectedPosts[:2], posts)
+
+		// The next page starts after the last post of this one
+		cursor, err := DecodeCursor(next)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedPosts[1].ID, cursor.ID.ObjectID())
 	})
 }
 
@@ -116,7 +139,7 @@ func TestFindPost(t *testing.T) {
 		ns := mt.DB.Name() + "." + mt.Coll.Name()
 		first := mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
 			{Key: "_id", Value: expectedPosts[0].ID},
-			{Key: "user", Value: defaultUser},
+			{Key: "user_id", Value: defaultUser.ID},
 			{Key: "movie", Value: bson.D{{Key: "id", Value: expectedPosts[0].Movie.ID}, {Key: "title", Value: expectedPosts[0].Movie.Title}, {Key: "poster_path", Value: expectedPosts[0].Movie.PosterPath}, {Key: "tagline", Value: expectedPosts[0].Movie.Tagline}}},
 			{Key: "content", Value: expectedPosts[0].Content},
 		})
@@ -126,7 +149,7 @@ func TestFindPost(t *testing.T) {
 		store := &PostStore{Collection: mt.Coll}
 
 		// Call the function that we are testing
-		post, err := store.FindPostByID(expectedPosts[0].ID.Hex(), context.TODO())
+		post, err := store.FindPostByID(expectedPosts[0].ID.Hex(), primitive.NilObjectID, context.TODO())
 
 		// Assert the function did not return an error
 		assert.Nil(t, err)
@@ -143,7 +166,7 @@ func TestUpdateUserPost(t *testing.T) {
 		// Mock the expected result returned from the FindOneAndUpdate() function
 		expectedPost := bson.D{
 			{Key: "_id", Value: expectedPosts[0].ID},
-			{Key: "user", Value: defaultUser},
+			{Key: "user_id", Value: defaultUser.ID},
 			{Key: "movie", Value: bson.D{{Key: "id", Value: expectedPosts[0].Movie.ID}, {Key: "title", Value: expectedPosts[0].Movie.Title}, {Key: "poster_path", Value: expectedPosts[0].Movie.PosterPath}, {Key: "tagline", Value: expectedPosts[0].Movie.Tagline}}},
 			{Key: "content", Value: expectedPosts[0].Content},
 		}
@@ -153,7 +176,7 @@ func TestUpdateUserPost(t *testing.T) {
 		store := &PostStore{Collection: mt.Coll}
 
 		// Call the function that we are testing
-		err := store.UpdateUserPost(expectedPosts[0].ID.Hex(), expectedPosts[0].Content, expectedPosts[0].User.ID, context.TODO())
+		err := store.UpdateUserPost(expectedPosts[0].ID.Hex(), expectedPosts[0].Content, expectedPosts[0].UserID, context.TODO())
 
 		// Assert the function did not return an error
 		assert.Nil(t, err)
@@ -174,7 +197,7 @@ func TestDeleteUserPost(t *testing.T) {
 		store := &PostStore{Collection: mt.Coll}
 
 		// Call the function that we are testing
-		err := store.DeleteUserPost(expectedPosts[0].ID.Hex(), expectedPosts[0].User, context.TODO())
+		err := store.DeleteUserPost(expectedPosts[0].ID.Hex(), defaultUser, context.TODO())
 
 		// Assert the function did not return an error
 		assert.Nil(t, err)
@@ -188,7 +211,7 @@ func TestDeleteUserPost(t *testing.T) {
 		store := &PostStore{Collection: mt.Coll}
 
 		// Call the function that we are testing
-		err := store.DeleteUserPost(expectedPosts[0].ID.Hex(), expectedPosts[0].User, context.TODO())
+		err := store.DeleteUserPost(expectedPosts[0].ID.Hex(), defaultUser, context.TODO())
 
 		// Assert the function returned an error
 		assert.NotNil(t, err)
@@ -212,3 +235,24 @@ func TestSave(t *testing.T) {
 		assert.Nil(t, err)
 	})
 }
+
+func TestFindPostWithViewerReaction(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("viewer liked the post", func(mt *mtest.T) {
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: expectedPosts[0].ID},
+			{Key: "user_id", Value: defaultUser.ID},
+			{Key: "reaction_counts", Value: bson.D{{Key: "like", Value: 3}, {Key: "love", Value: 1}}},
+			{Key: "viewer_reaction", Value: ReactionLike},
+		}))
+
+		store := &PostStore{Collection: mt.Coll}
+		post, err := store.FindPostByID(expectedPosts[0].ID.Hex(), defaultUser.ID, context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]int{"like": 3, "love": 1}, post.ReactionCounts)
+		assert.Equal(t, ReactionLike, post.ViewerReaction)
+		assert.Nil(t, post.Reactions)
+	})
+}
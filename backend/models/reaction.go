@@ -0,0 +1,271 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"infy/db"
+	"infy/utils"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Reaction types available unless REACTION_TYPES configures others
+const (
+	ReactionLike      = "like"
+	ReactionDislike   = "dislike"
+	ReactionLove      = "love"
+	ReactionLaugh     = "laugh"
+	ReactionMindBlown = "mind_blown"
+	ReactionSad       = "sad"
+	ReactionMustWatch = "must_watch"
+)
+
+// DefaultReactionTypes are the reactions users can give when none are configured
+var DefaultReactionTypes = []string{ReactionLike, ReactionDislike, ReactionLove, ReactionLaugh, ReactionMindBlown, ReactionSad, ReactionMustWatch}
+
+// reactionTypePattern keeps reaction types usable as field names of the reaction counts
+var reactionTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// ReactionSet is the set of reactions users can give to posts and comments.
+type ReactionSet struct {
+	types   []string
+	allowed map[string]struct{}
+}
+
+// NewReactionSet creates a reaction set from a list of reaction types, which must be short lowercase words
+func NewReactionSet(types []string) (*ReactionSet, error) {
+	if len(types) == 0 {
+		return nil, fmt.Errorf("no reaction types")
+	}
+
+	set := &ReactionSet{allowed: make(map[string]struct{}, len(types))}
+	for _, reactionType := range types {
+		if !reactionTypePattern.MatchString(reactionType) {
+			return nil, fmt.Errorf("invalid reaction type %q", reactionType)
+		}
+		if set.Has(reactionType) {
+			return nil, fmt.Errorf("duplicate reaction type %q", reactionType)
+		}
+
+		set.types = append(set.types, reactionType)
+		set.allowed[reactionType] = struct{}{}
+	}
+
+	return set, nil
+}
+
+// ReactionSetFromEnv builds the reaction set from the comma separated REACTION_TYPES, or DefaultReactionTypes if
+// it is not set.
+func ReactionSetFromEnv() (*ReactionSet, error) {
+	value := utils.GetEnv("REACTION_TYPES", "")
+	if value == "" {
+		return NewReactionSet(DefaultReactionTypes)
+	}
+
+	var types []string
+	for _, reactionType := range strings.Split(value, ",") {
+		types = append(types, strings.TrimSpace(reactionType))
+	}
+
+	return NewReactionSet(types)
+}
+
+// Has returns whether users can give reactions of the type
+func (s *ReactionSet) Has(reactionType string) bool {
+	_, ok := s.allowed[reactionType]
+	return ok
+}
+
+// Types returns the reaction types in the order they were configured
+func (s *ReactionSet) Types() []string {
+	return s.types
+}
+
+// Reaction is the reaction of a user to a post or comment. Each user has at most one reaction per post or comment.
+type Reaction struct {
+	UserID primitive.ObjectID `json:"-" bson:"user_id"`
+	User   *Author            `json:"user" bson:"-"` // Set by AuthorLoader when listing reactions
+	Type   string             `json:"type" bson:"type"`
+}
+
+// ReactionStore changes and lists the reactions to the posts or comments in its collection, which store their
+// reactions and the number of reactions of each type.
+type ReactionStore struct {
+	Collection *mongo.Collection
+}
+
+// reactionCounts counts the reactions of each type in the reactions of a document
+var reactionCounts = bson.M{"$arrayToObject": bson.M{"$map": bson.M{
+	"input": bson.M{"$setUnion": bson.A{bson.M{"$ifNull": bson.A{"$reactions.type", bson.A{}}}}},
+	"as":    "type",
+	"in": bson.M{
+		"k": "$$type",
+		"v": bson.M{"$size": bson.M{"$filter": bson.M{"input": "$reactions", "cond": bson.M{"$eq": bson.A{"$$this.type", "$$type"}}}}},
+	},
+}}}
+
+// reactionUpdate replaces the user's reaction and recounts the reactions in one update, so concurrent reactions
+// cannot get the counts out of sync. An empty reaction type just removes the user's reaction. With toggle, a
+// reaction of the type the user already gave is removed instead.
+func reactionUpdate(userID primitive.ObjectID, reactionType string, toggle bool) mongo.Pipeline {
+	reactions := bson.M{"$ifNull": bson.A{"$reactions", bson.A{}}}
+	otherReactions := bson.M{"$filter": bson.M{"input": reactions, "cond": bson.M{"$ne": bson.A{"$$this.user_id", userID}}}}
+
+	var newReactions interface{} = bson.A{}
+	if reactionType != "" {
+		newReactions = bson.M{"$literal": bson.A{Reaction{UserID: userID, Type: reactionType}}}
+	}
+	if reactionType != "" && toggle {
+		userReactions := bson.M{"$filter": bson.M{"input": reactions, "cond": bson.M{"$eq": bson.A{"$$this.user_id", userID}}}}
+		alreadyGiven := bson.M{"$in": bson.A{reactionType, bson.M{"$map": bson.M{"input": userReactions, "in": "$$this.type"}}}}
+		newReactions = bson.M{"$cond": bson.A{alreadyGiven, bson.A{}, newReactions}}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"reactions": bson.M{"$concatArrays": bson.A{otherReactions, newReactions}}}}},
+		{{Key: "$set", Value: bson.M{"reaction_counts": reactionCounts}}},
+	}
+}
+
+// viewerReaction reads the type of the viewer's reaction, which is missing if the viewer did not react
+func viewerReaction(viewerID primitive.ObjectID) bson.M {
+	viewerReactions := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$reactions", bson.A{}}},
+		"cond":  bson.M{"$eq": bson.A{"$$this.user_id", viewerID}},
+	}}
+
+	return bson.M{"$arrayElemAt": bson.A{bson.M{"$map": bson.M{"input": viewerReactions, "in": "$$this.type"}}, 0}}
+}
+
+// ReactionState is the reaction of a user to a post or comment and the number of reactions of each type
+type ReactionState struct {
+	Reaction string         `bson:"viewer_reaction,omitempty"` // Empty if the user did not react
+	Counts   map[string]int `bson:"reaction_counts"`
+}
+
+// SetReaction sets the user's reaction to the post or comment with the given ID, or removes it if the reaction type
+// is empty. It returns the user's reaction and the number of reactions of each type after the change.
+func (store *ReactionStore) SetReaction(targetID string, userID primitive.ObjectID, reactionType string, ctx context.Context) (*ReactionState, error) {
+	return store.updateReaction(targetID, userID, reactionType, false, ctx)
+}
+
+// ToggleReaction sets the user's reaction to the post or comment with the given ID, or removes it if the user
+// already gave a reaction of that type. It returns the user's reaction and the number of reactions of each type
+// after the change.
+func (store *ReactionStore) ToggleReaction(targetID string, userID primitive.ObjectID, reactionType string, ctx context.Context) (*ReactionState, error) {
+	return store.updateReaction(targetID, userID, reactionType, true, ctx)
+}
+
+// updateReaction changes the user's reaction with reactionUpdate and reads the reaction state after the change
+func (store *ReactionStore) updateReaction(targetID string, userID primitive.ObjectID, reactionType string, toggle bool, ctx context.Context) (*ReactionState, error) {
+	// Encode the ID to an ObjectID type
+	objectID, err := primitive.ObjectIDFromHex(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	projection := bson.M{"reaction_counts": 1, "viewer_reaction": viewerReaction(userID)}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(projection)
+
+	var state ReactionState
+	err = store.Collection.FindOneAndUpdate(ctx, bson.M{"_id": objectID}, reactionUpdate(userID, reactionType, toggle), opts).Decode(&state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// FindReactions finds a page of the reactions to the post or comment with the given ID, only of the given type
+// unless it is empty. The page is ordered by the IDs of the users who reacted.
+func (store *ReactionStore) FindReactions(targetID, reactionType string, ctx context.Context, page Page) ([]*Reaction, string, error) {
+	// Encode the ID to an ObjectID type
+	objectID, err := primitive.ObjectIDFromHex(targetID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": objectID}}},
+		{{Key: "$unwind", Value: "$reactions"}},
+		{{Key: "$replaceWith", Value: bson.M{"_id": "$reactions.user_id", "user_id": "$reactions.user_id", "type": "$reactions.type"}}},
+	}
+	if reactionType != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"type": reactionType}}})
+	}
+
+	return aggregatePage[Reaction](ctx, store.Collection, pipeline, page)
+}
+
+// RemoveUserReactions removes the reactions of the user from all posts and comments, adjusting their counts
+func RemoveUserReactions(userID primitive.ObjectID, ctx context.Context) error {
+	return removeUserReactions(userID, []*mongo.Collection{db.PostsCollection(), db.CommentsCollection()}, ctx)
+}
+
+// removeUserReactions removes the reactions of the user from the documents in the collections
+func removeUserReactions(userID primitive.ObjectID, collections []*mongo.Collection, ctx context.Context) error {
+	for _, collection := range collections {
+		if _, err := collection.UpdateMany(ctx, bson.M{"reactions.user_id": userID}, reactionUpdate(userID, "", false)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrateTypedReactions turns the likes and dislikes of posts and comments saved before reactions had a type into
+// like and dislike reactions, and counts them. It is safe to run on every start.
+func MigrateTypedReactions(ctx context.Context) (int64, error) {
+	var migrated int64
+
+	// Posts stored a reaction per user with whether they liked or disliked the post
+	reactions := bson.M{"$ifNull": bson.A{"$reactions", bson.A{}}}
+	postReactions := bson.M{"$map": bson.M{
+		"input": bson.M{"$filter": bson.M{"input": reactions, "cond": bson.M{"$or": bson.A{"$$this.liked", "$$this.disliked"}}}},
+		"in": bson.M{
+			"user_id": "$$this.user_id",
+			"type":    bson.M{"$cond": bson.A{"$$this.liked", ReactionLike, ReactionDislike}},
+		},
+	}}
+
+	// Comments stored the IDs of the users who liked and disliked them
+	likedBy := bson.M{"$ifNull": bson.A{"$liked_by", bson.A{}}}
+	dislikedBy := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$disliked_by", bson.A{}}},
+		"cond":  bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$this", likedBy}}}},
+	}}
+	commentReactions := bson.M{"$concatArrays": bson.A{
+		bson.M{"$map": bson.M{"input": likedBy, "in": bson.M{"user_id": "$$this", "type": ReactionLike}}},
+		bson.M{"$map": bson.M{"input": dislikedBy, "in": bson.M{"user_id": "$$this", "type": ReactionDislike}}},
+	}}
+
+	migrations := []struct {
+		collection *mongo.Collection
+		reactions  bson.M
+		unset      bson.A
+	}{
+		{db.PostsCollection(), postReactions, bson.A{"likes", "dislikes"}},
+		{db.CommentsCollection(), commentReactions, bson.A{"likes", "dislikes", "liked_by", "disliked_by"}},
+	}
+
+	for _, migration := range migrations {
+		update := mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"reactions": migration.reactions}}},
+			{{Key: "$set", Value: bson.M{"reaction_counts": reactionCounts}}},
+			{{Key: "$unset", Value: migration.unset}},
+		}
+
+		result, err := migration.collection.UpdateMany(ctx, bson.M{"reaction_counts": bson.M{"$exists": false}}, update)
+		if err != nil {
+			return migrated, err
+		}
+		migrated += result.ModifiedCount
+	}
+
+	return migrated, nil
+}
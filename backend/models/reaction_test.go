@@ -0,0 +1,123 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestNewReactionSet(t *testing.T) {
+	set, err := NewReactionSet(DefaultReactionTypes)
+	assert.Nil(t, err)
+	assert.True(t, set.Has(ReactionMustWatch))
+	assert.False(t, set.Has("angry"))
+	assert.Equal(t, DefaultReactionTypes, set.Types())
+
+	// Types are used as field names of the counts, so operators and dots are rejected
+	for _, types := range [][]string{nil, {"$inc"}, {"must.watch"}, {"Love"}, {"like", "like"}} {
+		_, err := NewReactionSet(types)
+		assert.NotNil(t, err, types)
+	}
+}
+
+func TestReactionSetFromEnv(t *testing.T) {
+	t.Setenv("REACTION_TYPES", "like, popcorn")
+
+	set, err := ReactionSetFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"like", "popcorn"}, set.Types())
+}
+
+func TestSetReaction(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "_id", Value: expectedPosts[0].ID},
+				{Key: "reaction_counts", Value: bson.D{{Key: "love", Value: 2}}},
+				{Key: "viewer_reaction", Value: ReactionLove},
+			}},
+		})
+
+		store := &ReactionStore{Collection: mt.Coll}
+		state, err := store.SetReaction(expectedPosts[0].ID.Hex(), defaultUser.ID, ReactionLove, context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, &ReactionState{Reaction: ReactionLove, Counts: map[string]int{"love": 2}}, state)
+
+		// The reaction and the counts are changed in a single update
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "findAndModify", event.CommandName)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+
+		store := &ReactionStore{Collection: mt.Coll}
+		_, err := store.SetReaction(expectedPosts[0].ID.Hex(), defaultUser.ID, "", context.TODO())
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+	})
+
+	mt.Run("invalid ID", func(mt *mtest.T) {
+		store := &ReactionStore{Collection: mt.Coll}
+		_, err := store.SetReaction("invalid", defaultUser.ID, ReactionLove, context.TODO())
+		assert.Equal(t, primitive.ErrInvalidHex, err)
+	})
+}
+
+func TestToggleReaction(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("taken back", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{{Key: "_id", Value: expectedPosts[0].ID}, {Key: "reaction_counts", Value: bson.D{}}}},
+		})
+
+		store := &ReactionStore{Collection: mt.Coll}
+		state, err := store.ToggleReaction(expectedPosts[0].ID.Hex(), defaultUser.ID, ReactionLike, context.TODO())
+		assert.Nil(t, err)
+		assert.Empty(t, state.Reaction)
+		assert.Empty(t, state.Counts)
+
+		// Whether the reaction is given or taken back is decided by the update itself
+		event := mt.GetStartedEvent()
+		assert.Equal(t, "findAndModify", event.CommandName)
+		assert.Contains(t, event.Command.Lookup("update").String(), "$cond")
+	})
+
+	mt.Run("invalid ID", func(mt *mtest.T) {
+		store := &ReactionStore{Collection: mt.Coll}
+		_, err := store.ToggleReaction("invalid", defaultUser.ID, ReactionLike, context.TODO())
+		assert.Equal(t, primitive.ErrInvalidHex, err)
+	})
+}
+
+func TestFindReactions(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("next page", func(mt *mtest.T) {
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		users := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: users[0]}, {Key: "user_id", Value: users[0]}, {Key: "type", Value: ReactionSad}},
+			bson.D{{Key: "_id", Value: users[1]}, {Key: "user_id", Value: users[1]}, {Key: "type", Value: ReactionSad}},
+		))
+
+		store := &ReactionStore{Collection: mt.Coll}
+		reactions, next, err := store.FindReactions(expectedPosts[0].ID.Hex(), ReactionSad, context.TODO(), Page{Limit: 1})
+		assert.Nil(t, err)
+		assert.Equal(t, []*Reaction{{UserID: users[0], Type: ReactionSad}}, reactions)
+
+		cursor, err := DecodeCursor(next)
+		assert.Nil(t, err)
+		assert.Equal(t, users[0], cursor.ID.ObjectID())
+	})
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"infy/db"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Roles a user can have, from least to most privileged
+const (
+	RoleUser       = "user"
+	RoleModerator  = "moderator"
+	RoleAdmin      = "admin"
+	RoleSuperadmin = "superadmin"
+)
+
+// Permissions granted by roles
+const (
+	PermPostsDeleteAny    = "posts.delete_any"
+	PermCommentsDeleteAny = "comments.delete_any"
+	PermReportsReview     = "reports.review"
+	PermUsersView         = "users.view"
+	PermUsersUnlock       = "users.unlock"
+	PermUsersManageRoles  = "users.manage_roles"
+	PermCacheView         = "cache.view"
+)
+
+// roles lists the roles by rank, a role can only manage the roles ranked below it
+var roles = []string{RoleUser, RoleModerator, RoleAdmin, RoleSuperadmin}
+
+var moderatorPermissions = []string{PermPostsDeleteAny, PermCommentsDeleteAny, PermReportsReview}
+
+var adminPermissions = append(slices.Clone(moderatorPermissions), PermUsersView, PermUsersUnlock, PermUsersManageRoles, PermCacheView)
+
+// rolePermissions maps each role to the permissions it grants. Superadmins have the same permissions as admins,
+// but their rank lets them manage admins.
+var rolePermissions = map[string][]string{
+	RoleUser:       {},
+	RoleModerator:  moderatorPermissions,
+	RoleAdmin:      adminPermissions,
+	RoleSuperadmin: adminPermissions,
+}
+
+// Errors returned when a role change is not allowed
+var (
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrCannotChangeOwnRole = errors.New("users cannot change their own role")
+	ErrRoleNotManageable   = errors.New("role is not below the acting user's role")
+)
+
+// IsValidRole checks if the role is one of the known roles
+func IsValidRole(role string) bool {
+	return slices.Contains(roles, role)
+}
+
+// roleRank returns the position of the role in the hierarchy. Users saved before roles existed count as regular users.
+func roleRank(role string) int {
+	if rank := slices.Index(roles, role); rank >= 0 {
+		return rank
+	}
+
+	return 0
+}
+
+// Permissions returns the permissions granted by the user's role
+func (u *User) Permissions() []string {
+	if permissions, ok := rolePermissions[u.Role]; ok {
+		return permissions
+	}
+
+	return []string{}
+}
+
+// HasPermission checks if the user's role grants the permission
+func (u *User) HasPermission(permission string) bool {
+	return slices.Contains(u.Permissions(), permission)
+}
+
+// IsStaff checks if the user has any role above a regular user
+func (u *User) IsStaff() bool {
+	return roleRank(u.Role) > 0
+}
+
+// CanAssignRole checks if the actor may give the target the role. Roles can only be managed by users with the
+// users.manage_roles permission, never for themselves, and only for users and roles ranked below their own,
+// so nobody can grant more than they have.
+func CanAssignRole(actor, target *User, role string) error {
+	if !IsValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	if !actor.HasPermission(PermUsersManageRoles) {
+		return ErrRoleNotManageable
+	}
+
+	if actor.ID == target.ID {
+		return ErrCannotChangeOwnRole
+	}
+
+	actorRank := roleRank(actor.Role)
+	if roleRank(target.Role) >= actorRank || roleRank(role) >= actorRank {
+		return ErrRoleNotManageable
+	}
+
+	return nil
+}
+
+// SetRole changes the role of a user, only if it is still the role the change was checked against
+func SetRole(userID primitive.ObjectID, currentRole, role string, ctx context.Context) error {
+	filter := bson.M{"_id": userID, "role": currentRole}
+	result, err := db.UsersCollection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// MigrateAdminRoles gives users saved before roles existed the admin or user role depending on their old isAdmin flag.
+// It is safe to run on every start.
+func MigrateAdminRoles(ctx context.Context) (int64, error) {
+	return migrateAdminRoles(db.UsersCollection(), ctx)
+}
+
+// migrateAdminRoles migrates the users in the collection, admins first. Each step only matches its own users, since
+// the flag is removed with the migration and could not be recovered if an admin were given the user role.
+func migrateAdminRoles(collection *mongo.Collection, ctx context.Context) (int64, error) {
+	var migrated int64
+
+	migrations := []struct {
+		isAdmin interface{}
+		role    string
+	}{
+		{true, RoleAdmin},
+		{bson.M{"$ne": true}, RoleUser},
+	}
+
+	for _, migration := range migrations {
+		filter := bson.M{"role": bson.M{"$exists": false}, "isAdmin": migration.isAdmin}
+
+		result, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"role": migration.role}, "$unset": bson.M{"isAdmin": ""}})
+		if err != nil {
+			return migrated, err
+		}
+		migrated += result.ModifiedCount
+	}
+
+	return migrated, nil
+}
+
+// PromoteToSuperadmin gives the user with the email the superadmin role. Superadmins cannot be created through
+// the API, so the first one is set up from the configuration.
+func PromoteToSuperadmin(email string, ctx context.Context) error {
+	user, err := FindUserByEmail(email, ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.UsersCollection().UpdateByID(ctx, user.ID, bson.M{"$set": bson.M{"role": RoleSuperadmin}})
+
+	return err
+}
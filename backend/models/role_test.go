@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func userWithRole(role string) *User {
+	return &User{ID: primitive.NewObjectID(), Role: role}
+}
+
+func TestHasPermission(t *testing.T) {
+	assert.False(t, userWithRole(RoleUser).HasPermission(PermPostsDeleteAny))
+	assert.False(t, userWithRole("").HasPermission(PermPostsDeleteAny))
+	assert.True(t, userWithRole(RoleModerator).HasPermission(PermReportsReview))
+	assert.False(t, userWithRole(RoleModerator).HasPermission(PermUsersManageRoles))
+	assert.True(t, userWithRole(RoleAdmin).HasPermission(PermUsersManageRoles))
+	assert.True(t, userWithRole(RoleSuperadmin).HasPermission(PermCacheView))
+}
+
+func TestIsStaff(t *testing.T) {
+	assert.False(t, userWithRole(RoleUser).IsStaff())
+	assert.False(t, userWithRole("unknown").IsStaff())
+	assert.True(t, userWithRole(RoleModerator).IsStaff())
+}
+
+func TestCanAssignRole(t *testing.T) {
+	admin := userWithRole(RoleAdmin)
+	superadmin := userWithRole(RoleSuperadmin)
+
+	assert.Nil(t, CanAssignRole(admin, userWithRole(RoleUser), RoleModerator))
+	assert.Nil(t, CanAssignRole(admin, userWithRole(RoleModerator), RoleUser))
+	assert.Nil(t, CanAssignRole(superadmin, userWithRole(RoleUser), RoleAdmin))
+	assert.Nil(t, CanAssignRole(superadmin, userWithRole(RoleAdmin), RoleUser))
+
+	// Nobody can grant a role as high as their own or manage someone ranked as high
+	assert.ErrorIs(t, CanAssignRole(admin, userWithRole(RoleUser), RoleAdmin), ErrRoleNotManageable)
+	assert.ErrorIs(t, CanAssignRole(admin, userWithRole(RoleAdmin), RoleUser), ErrRoleNotManageable)
+	assert.ErrorIs(t, CanAssignRole(superadmin, userWithRole(RoleUser), RoleSuperadmin), ErrRoleNotManageable)
+	assert.ErrorIs(t, CanAssignRole(userWithRole(RoleModerator), userWithRole(RoleUser), RoleUser), ErrRoleNotManageable)
+
+	assert.ErrorIs(t, CanAssignRole(superadmin, superadmin, RoleUser), ErrCannotChangeOwnRole)
+	assert.ErrorIs(t, CanAssignRole(superadmin, userWithRole(RoleUser), "owner"), ErrInvalidRole)
+}
+
+func TestMigrateAdminRoles(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("admins stay admins", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+		)
+
+		migrated, err := migrateAdminRoles(mt.Coll, context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), migrated)
+
+		// Admins are migrated first, and the user step cannot match anyone still flagged as an admin
+		admins := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "true", admins.Lookup("q", "isAdmin").String())
+		assert.Equal(t, RoleAdmin, admins.Lookup("u", "$set", "role").StringValue())
+
+		users := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "true", users.Lookup("q", "isAdmin", "$ne").String())
+		assert.Equal(t, RoleUser, users.Lookup("u", "$set", "role").StringValue())
+	})
+}
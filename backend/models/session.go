@@ -0,0 +1,206 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Session is a logged in device. Only the hash of its refresh token is stored.
+type Session struct {
+	ID                primitive.ObjectID `json:"id" bson:"_id"`
+	UserID            primitive.ObjectID `json:"-" bson:"user_id"`
+	TokenHash         string             `json:"-" bson:"token_hash"`
+	PreviousTokenHash string             `json:"-" bson:"previous_token_hash,omitempty"` // Detects reuse of a rotated refresh token
+	Device            string             `json:"device" bson:"device"`                   // User agent of the client
+	IP                string             `json:"ip" bson:"ip"`
+	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`
+	LastUsedAt        time.Time          `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt         time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt         *time.Time         `json:"-" bson:"revoked_at,omitempty"`
+	ReauthenticatedAt *time.Time         `json:"-" bson:"reauthenticated_at,omitempty"` // Last login at the provider to confirm a sensitive change
+}
+
+type SessionStore struct {
+	Collection *mongo.Collection
+}
+
+// NewSession creates a new session for the user that expires after the given duration
+func NewSession(userID primitive.ObjectID, tokenHash, device, ip string, ttl time.Duration) *Session {
+	now := time.Now()
+	return &Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		TokenHash:  tokenHash,
+		Device:     device,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+}
+
+// IsActive checks if the session has not been revoked and has not expired
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// EnsureIndexes creates the indexes used to look up sessions and the TTL index that removes expired sessions
+func (store *SessionStore) EnsureIndexes(ctx context.Context) error {
+	_, err := store.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "previous_token_hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+
+	return err
+}
+
+// Save saves a session to the database
+func (store *SessionStore) Save(s *Session, ctx context.Context) error {
+	_, err := store.Collection.InsertOne(ctx, s)
+
+	return err
+}
+
+// FindSessionByID finds a session by ID
+func (store *SessionStore) FindSessionByID(id string, ctx context.Context) (*Session, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	err = store.Collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// FindSessionByTokenHash finds the session a refresh token belongs to
+func (store *SessionStore) FindSessionByTokenHash(tokenHash string, ctx context.Context) (*Session, error) {
+	var session Session
+	err := store.Collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// FindSessionByPreviousTokenHash finds the session a rotated refresh token used to belong to
+func (store *SessionStore) FindSessionByPreviousTokenHash(tokenHash string, ctx context.Context) (*Session, error) {
+	var session Session
+	err := store.Collection.FindOne(ctx, bson.M{"previous_token_hash": tokenHash}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// RotateSession replaces the refresh token of an active session and extends it.
+// It returns mongo.ErrNoDocuments if the token was already rotated by a concurrent request or the session was revoked.
+func (store *SessionStore) RotateSession(s *Session, newTokenHash string, ttl time.Duration, ctx context.Context) error {
+	now := time.Now()
+
+	// Matching on the current hash makes the rotation atomic, only one request can use a refresh token
+	filter := bson.M{"_id": s.ID, "token_hash": s.TokenHash, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{
+		"token_hash":          newTokenHash,
+		"previous_token_hash": s.TokenHash,
+		"last_used_at":        now,
+		"expires_at":          now.Add(ttl),
+	}}
+
+	result, err := store.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	s.PreviousTokenHash = s.TokenHash
+	s.TokenHash = newTokenHash
+	s.LastUsedAt = now
+	s.ExpiresAt = now.Add(ttl)
+
+	return nil
+}
+
+// FindActiveSessionsByUserID finds the sessions of a user that have not been revoked or expired, most recently used first
+func (store *SessionStore) FindActiveSessionsByUserID(userID primitive.ObjectID, ctx context.Context) ([]*Session, error) {
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": time.Now()}}
+	opts := options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}})
+
+	cursor, err := store.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []*Session{}
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// MarkReauthenticated records that the user logged in again during their active session.
+// It returns mongo.ErrNoDocuments if the user has no such active session.
+func (store *SessionStore) MarkReauthenticated(id string, userID primitive.ObjectID, ctx context.Context) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": objectID, "user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	result, err := store.Collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"reauthenticated_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// RevokeSession revokes a session of the user. It returns mongo.ErrNoDocuments if the user has no such active session.
+func (store *SessionStore) RevokeSession(id string, userID primitive.ObjectID, ctx context.Context) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": objectID, "user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	result, err := store.Collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes every session of the user except the given one, which may be the zero ID to revoke all of them
+func (store *SessionStore) RevokeUserSessions(userID, exceptID primitive.ObjectID, ctx context.Context) error {
+	filter := bson.M{"user_id": userID, "_id": bson.M{"$ne": exceptID}, "revoked_at": bson.M{"$exists": false}}
+	_, err := store.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+
+	return err
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSessionIsActive(t *testing.T) {
+	session := NewSession(primitive.NewObjectID(), "hash", "test-agent", "127.0.0.1", time.Hour)
+	assert.True(t, session.IsActive())
+
+	revokedAt := time.Now()
+	session.RevokedAt = &revokedAt
+	assert.False(t, session.IsActive())
+
+	expired := NewSession(primitive.NewObjectID(), "hash", "test-agent", "127.0.0.1", -time.Minute)
+	assert.False(t, expired.IsActive())
+}
+
+func TestRotateSession(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		// Mock the expected result returned from the UpdateOne() function
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		store := &SessionStore{Collection: mt.Coll}
+		session := NewSession(primitive.NewObjectID(), "old-hash", "test-agent", "127.0.0.1", time.Hour)
+
+		err := store.RotateSession(session, "new-hash", time.Hour, context.TODO())
+
+		// Assert the session now holds the new hash and remembers the old one
+		assert.Nil(t, err)
+		assert.Equal(t, "new-hash", session.TokenHash)
+		assert.Equal(t, "old-hash", session.PreviousTokenHash)
+	})
+
+	mt.Run("already rotated", func(mt *mtest.T) {
+		// No document matches the old hash once another request has rotated it
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		store := &SessionStore{Collection: mt.Coll}
+		session := NewSession(primitive.NewObjectID(), "old-hash", "test-agent", "127.0.0.1", time.Hour)
+
+		err := store.RotateSession(session, "new-hash", time.Hour, context.TODO())
+
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+		assert.Equal(t, "old-hash", session.TokenHash)
+	})
+}
+
+func TestMarkReauthenticated(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		store := &SessionStore{Collection: mt.Coll}
+		err := store.MarkReauthenticated(primitive.NewObjectID().Hex(), primitive.NewObjectID(), context.TODO())
+
+		assert.Nil(t, err)
+	})
+
+	mt.Run("revoked", func(mt *mtest.T) {
+		// A revoked session or one of another user cannot be confirmed
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		store := &SessionStore{Collection: mt.Coll}
+		err := store.MarkReauthenticated(primitive.NewObjectID().Hex(), primitive.NewObjectID(), context.TODO())
+
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+}
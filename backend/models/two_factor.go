@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"infy/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TwoFactor holds the TOTP two-factor authentication settings of a user. Only Enabled is ever sent to clients.
+type TwoFactor struct {
+	Enabled       bool     `json:"enabled" bson:"enabled"`
+	Secret        string   `json:"-" bson:"secret,omitempty"`
+	PendingSecret string   `json:"-" bson:"pending_secret,omitempty"` // Secret being enrolled until the first code confirms it
+	RecoveryCodes []string `json:"-" bson:"recovery_codes,omitempty"` // Hashes of the unused recovery codes
+	LastUsedStep  int64    `json:"-" bson:"last_used_step,omitempty"` // Time step of the last accepted code, to reject replays
+}
+
+// SetPendingTwoFactorSecret stores the secret being enrolled until it is confirmed
+func SetPendingTwoFactorSecret(userID primitive.ObjectID, secret string, ctx context.Context) error {
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, bson.M{"$set": bson.M{"two_factor.pending_secret": secret}})
+
+	return err
+}
+
+// EnableTwoFactor turns on two-factor authentication with the confirmed secret and recovery code hashes
+func EnableTwoFactor(userID primitive.ObjectID, secret string, step int64, recoveryCodes []string, ctx context.Context) error {
+	update := bson.M{
+		"$set": bson.M{
+			"two_factor.enabled":        true,
+			"two_factor.secret":         secret,
+			"two_factor.recovery_codes": recoveryCodes,
+			"two_factor.last_used_step": step,
+		},
+		"$unset": bson.M{"two_factor.pending_secret": ""},
+	}
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, update)
+
+	return err
+}
+
+// DisableTwoFactor turns off two-factor authentication and removes the secret and recovery codes
+func DisableTwoFactor(userID primitive.ObjectID, ctx context.Context) error {
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, bson.M{"$set": bson.M{"two_factor": TwoFactor{}}})
+
+	return err
+}
+
+// SetRecoveryCodes replaces the user's recovery code hashes
+func SetRecoveryCodes(userID primitive.ObjectID, recoveryCodes []string, ctx context.Context) error {
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, bson.M{"$set": bson.M{"two_factor.recovery_codes": recoveryCodes}})
+
+	return err
+}
+
+// UseTwoFactorStep records the time step of an accepted code.
+// It returns mongo.ErrNoDocuments if a code of the same or a later step was already used.
+func UseTwoFactorStep(userID primitive.ObjectID, step int64, ctx context.Context) error {
+	// $not also matches users without a recorded step
+	filter := bson.M{"_id": userID, "two_factor.last_used_step": bson.M{"$not": bson.M{"$gte": step}}}
+	result, err := db.UsersCollection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"two_factor.last_used_step": step}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// UseRecoveryCode removes the recovery code hash from the user so it can only be used once.
+// It returns mongo.ErrNoDocuments if the user has no such code.
+func UseRecoveryCode(userID primitive.ObjectID, codeHash string, ctx context.Context) error {
+	filter := bson.M{"_id": userID, "two_factor.recovery_codes": codeHash}
+	result, err := db.UsersCollection().UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"two_factor.recovery_codes": codeHash}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
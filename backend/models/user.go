@@ -5,21 +5,35 @@ import (
 	"infy/db"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type User struct {
-	ID       primitive.ObjectID `json:"id" bson:"_id"`
-	Username string             `json:"username"`
-	Email    string             `json:"email"`
-	Password string             `json:"-" bson:"password"`
-	IsAdmin  bool               `json:"isAdmin" bson:"isAdmin"`
-	Profile  Profile            `json:"profile" bson:"profile"`
+	ID            primitive.ObjectID `json:"id" bson:"_id"`
+	Username      string             `json:"username"`
+	Email         string             `json:"email"`
+	EmailVerified bool               `json:"email_verified" bson:"email_verified"`
+	Password      string             `json:"-" bson:"password"`
+	Role          string             `json:"role" bson:"role"` // One of the Role constants, which decides the user's permissions
+	Profile       Profile            `json:"profile" bson:"profile"`
+	Identities    []Identity         `json:"identities" bson:"identities,omitempty"` // Linked OpenID Connect accounts
+	TwoFactor     TwoFactor          `json:"two_factor" bson:"two_factor"`
+	DeleteAfter   *time.Time         `json:"delete_after,omitempty" bson:"delete_after,omitempty"` // End of the grace period of a requested account deletion
+}
+
+// Identity is an account at an OpenID Connect provider the user can log in with
+type Identity struct {
+	Issuer   string    `json:"issuer" bson:"issuer"`
+	Subject  string    `json:"-" bson:"subject"`
+	Email    string    `json:"email" bson:"email"`
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
 }
 
 type Profile struct {
@@ -37,11 +51,41 @@ type Preferences struct {
 	Followers []primitive.ObjectID `json:"followers" bson:"followers,omitempty"` // IDs of users
 	WatchList []string             `json:"watchlist"`                            // Movie IDs
 	Watched   []string             `json:"watched"`                              // Movie IDs
+	Region    string               `json:"region" bson:"region,omitempty"`       // ISO 3166-1 country code used for watch providers
+	Services  []int                `json:"services" bson:"services,omitempty"`   // TMDB watch provider IDs the user subscribes to
+}
+
+// caseInsensitive compares strings ignoring case, so lookups match the unique email and username indexes
+var caseInsensitive = &options.Collation{Locale: "en", Strength: 2}
+
+// Names of the unique user indexes, used to tell which field a duplicate key error is about
+const (
+	UserEmailIndex    = "email_unique"
+	UserUsernameIndex = "username_unique"
+	UserIdentityIndex = "identity_unique"
+)
+
+// EnsureUserIndexes creates the unique indexes that keep emails and usernames from being registered twice in any casing,
+// and an OpenID Connect identity from being linked to two users
+func EnsureUserIndexes(ctx context.Context) error {
+	// Only users with linked identities are indexed, the others would all share an empty key
+	withIdentities := bson.M{"identities.subject": bson.M{"$exists": true}}
+
+	_, err := db.UsersCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName(UserEmailIndex).SetUnique(true).SetCollation(caseInsensitive)},
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetName(UserUsernameIndex).SetUnique(true).SetCollation(caseInsensitive)},
+		{
+			Keys:    bson.D{{Key: "identities.issuer", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().SetName(UserIdentityIndex).SetUnique(true).SetPartialFilterExpression(withIdentities),
+		},
+	})
+
+	return err
 }
 
 // NewUser creates a new user instance
 func NewUser(username, email, password string, profile *Profile) *User {
-	return &User{ID: primitive.NewObjectID(), Username: username, Email: email, Password: password, IsAdmin: false, Profile: *profile}
+	return &User{ID: primitive.NewObjectID(), Username: username, Email: email, Password: password, Role: RoleUser, Profile: *profile}
 }
 
 // NewProfile creates a new profile instance
@@ -58,7 +102,7 @@ func NewPreferences() *Preferences {
 func FindUserByEmail(email string, ctx context.Context) (*User, error) {
 	var user User
 
-	err := db.UsersCollection().FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	err := db.UsersCollection().FindOne(ctx, bson.M{"email": email}, options.FindOne().SetCollation(caseInsensitive)).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
@@ -80,10 +124,11 @@ func FindUserByID(id string, ctx context.Context) (*User, error) {
 	return &user, err
 }
 
+// FindUserByUsername finds a user by username
 func FindUserByUsername(username string, ctx context.Context) (*User, error) {
 	var user User
 
-	err := db.UsersCollection().FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	err := db.UsersCollection().FindOne(ctx, bson.M{"username": username}, options.FindOne().SetCollation(caseInsensitive)).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +136,62 @@ func FindUserByUsername(username string, ctx context.Context) (*User, error) {
 	return &user, nil
 }
 
+// FindUsersByIDs finds the users with the given IDs
+func FindUsersByIDs(ids []primitive.ObjectID, ctx context.Context) ([]*User, error) {
+	users := []*User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	cursor, err := db.UsersCollection().Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// FindUserByIdentity finds the user an OpenID Connect identity is linked to
+func FindUserByIdentity(issuer, subject string, ctx context.Context) (*User, error) {
+	var user User
+
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"issuer": issuer, "subject": subject}}}
+	err := db.UsersCollection().FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// LinkIdentity links an OpenID Connect identity to the user, replacing an identity of the same issuer. The identity
+// is replaced in a single update, so a user whose only login is the identity never ends up without one.
+func LinkIdentity(userID primitive.ObjectID, identity Identity, ctx context.Context) error {
+	otherIdentities := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$identities", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.issuer", identity.Issuer}},
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"identities": bson.M{"$concatArrays": bson.A{otherIdentities, bson.M{"$literal": bson.A{identity}}}}}}},
+	}
+
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, update)
+
+	return err
+}
+
+// UnlinkIdentity removes the user's identity of the given issuer
+func UnlinkIdentity(userID primitive.ObjectID, issuer string, ctx context.Context) error {
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, bson.M{"$pull": bson.M{"identities": bson.M{"issuer": issuer}}})
+
+	return err
+}
+
 // FollowUser adds a user to the following list and updates the other user's followers list
 func (u *User) FollowUser(id string, ctx context.Context) error {
 	userId, err := primitive.ObjectIDFromHex(id)
@@ -135,11 +236,21 @@ func (u *User) UnfollowUser(id string, ctx context.Context) error {
 	return nil
 }
 
-// GetJwtToken returns a JWT token with the user's ID as the subject
-func (u *User) GetJwtToken(exp time.Time) (string, error) {
-	claims := &jwt.RegisteredClaims{
-		Subject:   u.ID.Hex(),
-		ExpiresAt: jwt.NewNumericDate(exp),
+// AccessClaims are the claims of the short lived access tokens, tying each token to the session it was issued for
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
+}
+
+// GetJwtToken returns a JWT token with the user's ID as the subject for the given session
+func (u *User) GetJwtToken(sessionID string, exp time.Time) (string, error) {
+	claims := &AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   u.ID.Hex(),
+			ExpiresAt: jwt.NewNumericDate(exp),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		SessionID: sessionID,
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
@@ -163,6 +274,51 @@ func (u *User) Save(ctx context.Context) error {
 	return nil
 }
 
+// DuplicateUserField returns the field a duplicate key error from saving a user is about, or an empty string
+// if the error is not one. Two signups racing each other get past the earlier lookups and end up here.
+func DuplicateUserField(err error) string {
+	if !mongo.IsDuplicateKeyError(err) {
+		return ""
+	}
+
+	switch {
+	case strings.Contains(err.Error(), UserEmailIndex):
+		return "email"
+	case strings.Contains(err.Error(), UserUsernameIndex):
+		return "username"
+	}
+
+	return ""
+}
+
+// IsDuplicateIdentity returns whether the error is from linking an OpenID Connect identity that another user already
+// has. Two logins racing each other get past the earlier lookups and end up here.
+func IsDuplicateIdentity(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), UserIdentityIndex)
+}
+
+// SetEmailVerified marks the user's email as verified if it is still the address the verification was sent to
+func SetEmailVerified(userID primitive.ObjectID, email string, ctx context.Context) error {
+	filter := bson.M{"_id": userID, "email": email}
+	result, err := db.UsersCollection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"email_verified": true}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// UpdatePassword replaces the user's password hash
+func UpdatePassword(userID primitive.ObjectID, hashedPassword string, ctx context.Context) error {
+	_, err := db.UsersCollection().UpdateByID(ctx, userID, bson.M{"$set": bson.M{"password": hashedPassword}})
+
+	return err
+}
+
 // AddMovieToWatchedList adds a movie ID to the user's watched list
 func AddMovieToWatchedList(userID, movieID string, ctx context.Context) error {
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
@@ -190,139 +346,70 @@ func AddMovieToWatchlist(userID, movieID string, ctx context.Context) error {
 	return err
 }
 
-// RemoveMovieFromWatchedList removes a movie ID from the user's watched list
-func RemoveMovieFromWatchedList(userID, movieID string, ctx context.Context) error {
+// UpdateStreamingSettings sets the user's watch provider region and subscribed services
+func UpdateStreamingSettings(userID, region string, services []int, ctx context.Context) error {
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return err
 	}
 
-	update := bson.M{"$pull": bson.M{"profile.preferences.watched": movieID}}
+	update := bson.M{"$set": bson.M{"profile.preferences.region": region, "profile.preferences.services": services}}
 	_, err = db.UsersCollection().UpdateByID(ctx, userObjectID, update)
 
 	return err
 }
 
-// RemoveMovieFromWatchlist removes a movie ID from the user's watchlist
-func RemoveMovieFromWatchlist(userID, movieID string, ctx context.Context) error {
+// RemoveMovieFromWatchedList removes a movie ID from the user's watched list
+func RemoveMovieFromWatchedList(userID, movieID string, ctx context.Context) error {
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return err
 	}
 
-	update := bson.M{"$pull": bson.M{"profile.preferences.watchlist": movieID}}
+	update := bson.M{"$pull": bson.M{"profile.preferences.watched": movieID}}
 	_, err = db.UsersCollection().UpdateByID(ctx, userObjectID, update)
 
 	return err
 }
 
-func FindFollowedWhoWatchedMovie(userID, movieID string) ([]User, error) {
-	var users []User
+// RemoveMovieFromWatchlist removes a movie ID from the user's watchlist
+func RemoveMovieFromWatchlist(userID, movieID string, ctx context.Context) error {
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, err
-	}
-
-	pipeline := mongo.Pipeline{
-		bson.D{{Key: "$match", Value: bson.M{"_id": userObjectID}}},
-		bson.D{{Key: "$lookup", Value: bson.M{
-			"from": "users",
-			"let":  bson.M{"followingIds": "$preferences.following"},
-			"pipeline": mongo.Pipeline{
-				bson.D{{Key: "$match", Value: bson.M{"$expr": bson.M{"$in": []interface{}{"$_id", "$$followingIds"}}}}},
-				bson.D{{Key: "$match", Value: bson.M{"preferences.watched": movieID}}},
-			},
-			"as": "followedWhoWatched",
-		}}},
-		bson.D{{Key: "$unwind", Value: "$followedWhoWatched"}},
-		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$followedWhoWatched"}}},
-	}
-
-	ctx := context.TODO()
-	cursor, err := db.UsersCollection().Aggregate(ctx, pipeline)
-	if err != nil {
-		return nil, err
+		return err
 	}
-	defer cursor.Close(ctx)
 
-	if err = cursor.All(ctx, &users); err != nil {
-		return nil, err
-	}
+	update := bson.M{"$pull": bson.M{"profile.preferences.watchlist": movieID}}
+	_, err = db.UsersCollection().UpdateByID(ctx, userObjectID, update)
 
-	return users, nil
+	return err
 }
 
-// ToggleAdmin toggles the admin status of a user
-func ToggleAdmin(userID string, ctx context.Context) error {
-	// Convert the user ID to an ObjectID
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		return err
-	}
-
-	// Check if the user is already an admin
+// FindFollowedWhoWatchedMovie finds a page of the users followed by the user who have watched the movie
+func FindFollowedWhoWatchedMovie(userID, movieID string, ctx context.Context, page Page) ([]*Author, string, error) {
 	user, err := FindUserByID(userID, ctx)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	// Update to the opposite of the current value
-	update := bson.M{"$set": bson.M{"isAdmin": !user.IsAdmin}}
-	_, err = db.UsersCollection().UpdateOne(ctx, bson.M{"_id": userObjectID}, update)
-	if err != nil {
-		return err
+	if len(user.Profile.Preferences.Following) == 0 {
+		return []*Author{}, "", nil
 	}
 
-	return nil
-}
-
-// GetUsers returns all users
-func GetUsers() ([]User, error) {
-	var users []User
-	cursor, err := db.UsersCollection().Find(context.Background(), bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
+	filter := bson.M{"_id": bson.M{"$in": user.Profile.Preferences.Following}, "profile.preferences.watched": movieID}
 
-	if err = cursor.All(context.Background(), &users); err != nil {
-		return nil, err
-	}
+	return findPage[Author](ctx, db.UsersCollection(), filter, "", authorProjection, page)
+}
 
-	return users, nil
+// GetUsers returns a page of all users, newest first
+func GetUsers(ctx context.Context, page Page) ([]*User, string, error) {
+	return findPage[User](ctx, db.UsersCollection(), bson.M{}, "", nil, page)
 }
 
 // AddAvatar adds an avatar to the user's profile
 func AddAvatar(user *User, filepath string, ctx context.Context) error {
 	update := bson.M{"$set": bson.M{"profile.avatar": filepath}}
 	_, err := db.UsersCollection().UpdateByID(ctx, user.ID, update)
-	if err != nil {
-		return err
-	}
-
-	err = refreshUser(user, ctx)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// refreshUser updates the user in all posts and comments
-func refreshUser(user *User, ctx context.Context) error {
-	// Update the user in all posts
-	update := bson.M{"$set": bson.M{"user": user}}
-	_, err := db.PostsCollection().UpdateMany(ctx, bson.M{"user._id": user.ID}, update)
-	if err != nil {
-		return err
-	}
-
-	// Update the user in all comments
-	update = bson.M{"$set": bson.M{"user": user}}
-	_, err = db.CommentsCollection().UpdateMany(ctx, bson.M{"user._id": user.ID}, update)
-	if err != nil {
-		return err
-	}
 
-	return nil
+	return err
 }
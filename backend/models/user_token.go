@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Purposes of the single use tokens sent to users by email
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// UserToken is a single use, expiring token emailed to a user. Only the hash of the token is stored.
+type UserToken struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Purpose   string             `bson:"purpose"`
+	TokenHash string             `bson:"token_hash"`
+	Email     string             `bson:"email"` // Address the token was sent to
+	CreatedAt time.Time          `bson:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"`
+}
+
+type UserTokenStore struct {
+	Collection *mongo.Collection
+}
+
+// NewUserToken creates a new token for the user that expires after the given duration
+func NewUserToken(userID primitive.ObjectID, purpose, tokenHash, email string, ttl time.Duration) *UserToken {
+	now := time.Now()
+	return &UserToken{ID: primitive.NewObjectID(), UserID: userID, Purpose: purpose, TokenHash: tokenHash, Email: email, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+}
+
+// EnsureIndexes creates the unique token index and the TTL index that removes expired tokens
+func (store *UserTokenStore) EnsureIndexes(ctx context.Context) error {
+	_, err := store.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+
+	return err
+}
+
+// Save invalidates the user's unused tokens with the same purpose and saves the new token, so only the latest email works
+func (store *UserTokenStore) Save(t *UserToken, ctx context.Context) error {
+	filter := bson.M{"user_id": t.UserID, "purpose": t.Purpose, "used_at": bson.M{"$exists": false}}
+	_, err := store.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"used_at": t.CreatedAt}})
+	if err != nil {
+		return err
+	}
+
+	_, err = store.Collection.InsertOne(ctx, t)
+
+	return err
+}
+
+// usableTokenFilter matches the unused, unexpired token with the given hash and purpose
+func usableTokenFilter(tokenHash, purpose string, now time.Time) bson.M {
+	return bson.M{
+		"token_hash": tokenHash,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+}
+
+// FindToken returns an unused, unexpired token with the given purpose without using it up.
+// It returns mongo.ErrNoDocuments if there is no such token.
+func (store *UserTokenStore) FindToken(tokenHash, purpose string, ctx context.Context) (*UserToken, error) {
+	var token UserToken
+	err := store.Collection.FindOne(ctx, usableTokenFilter(tokenHash, purpose, time.Now())).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// ConsumeToken marks an unused, unexpired token with the given purpose as used and returns it.
+// It returns mongo.ErrNoDocuments if there is no such token, which also makes concurrent uses of the same token fail.
+func (store *UserTokenStore) ConsumeToken(tokenHash, purpose string, ctx context.Context) (*UserToken, error) {
+	now := time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var token UserToken
+	err := store.Collection.FindOneAndUpdate(ctx, usableTokenFilter(tokenHash, purpose, now), bson.M{"$set": bson.M{"used_at": now}}, opts).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
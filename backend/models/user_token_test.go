@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestConsumeToken(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		userID := primitive.NewObjectID()
+		usedAt := time.Now()
+
+		// Mock the expected result returned from the FindOneAndUpdate() function
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "user_id", Value: userID},
+			{Key: "purpose", Value: TokenPurposeResetPassword},
+			{Key: "token_hash", Value: "hash"},
+			{Key: "used_at", Value: usedAt},
+		}}))
+
+		store := &UserTokenStore{Collection: mt.Coll}
+		token, err := store.ConsumeToken("hash", TokenPurposeResetPassword, context.TODO())
+
+		assert.Nil(t, err)
+		assert.Equal(t, userID, token.UserID)
+		assert.NotNil(t, token.UsedAt)
+	})
+
+	mt.Run("used or expired", func(mt *mtest.T) {
+		// FindOneAndUpdate returns a null value when no unused token matches
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		store := &UserTokenStore{Collection: mt.Coll}
+		token, err := store.ConsumeToken("hash", TokenPurposeResetPassword, context.TODO())
+
+		assert.Nil(t, token)
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+}
+
+func TestFindToken(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		userID := primitive.NewObjectID()
+		ns := mt.DB.Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "user_id", Value: userID},
+			{Key: "purpose", Value: TokenPurposeResetPassword},
+			{Key: "token_hash", Value: "hash"},
+		}))
+
+		store := &UserTokenStore{Collection: mt.Coll}
+		token, err := store.FindToken("hash", TokenPurposeResetPassword, context.TODO())
+
+		assert.Nil(t, err)
+		assert.Equal(t, userID, token.UserID)
+		assert.Nil(t, token.UsedAt)
+
+		// Finding the token does not use it up
+		assert.Equal(t, "find", mt.GetStartedEvent().CommandName)
+	})
+}
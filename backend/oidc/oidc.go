@@ -0,0 +1,323 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infy/utils"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultScopes are the scopes requested when none are configured.
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// Config holds the settings of an OpenID Connect provider registered for this application.
+type Config struct {
+	Name         string // Shown to users, e.g. "Google"
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// ConfigFromEnv reads the provider configuration from the OIDC_* environment variables.
+// It returns false if no issuer is configured, in which case OIDC login is disabled.
+func ConfigFromEnv() (Config, bool) {
+	cfg := Config{
+		Name:         utils.GetEnv("OIDC_PROVIDER_NAME", "OpenID Connect"),
+		IssuerURL:    utils.GetEnv("OIDC_ISSUER_URL", ""),
+		ClientID:     utils.GetEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: utils.GetEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  utils.GetEnv("OIDC_REDIRECT_URL", "http://localhost:8000/auth/oidc/callback"),
+	}
+
+	return cfg, cfg.IssuerURL != ""
+}
+
+// Claims are the identity claims of a verified ID token.
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce             string           `json:"nonce"`
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"` // When the user last entered their credentials at the provider
+	Email             string           `json:"email"`
+	EmailVerified     bool             `json:"email_verified"`
+	Name              string           `json:"name"`
+	GivenName         string           `json:"given_name"`
+	FamilyName        string           `json:"family_name"`
+	PreferredUsername string           `json:"preferred_username"`
+}
+
+// Provider runs the authorization code flow with PKCE against an OpenID Connect provider.
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      map[string]*rsa.PublicKey
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvider creates a provider. The discovery document is fetched on first use so startup does not depend on the provider.
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("oidc: issuer URL, client ID and redirect URL are required")
+	}
+
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Provider{config: cfg, httpClient: httpClient}, nil
+}
+
+// Name returns the display name of the provider.
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// Issuer returns the issuer URL identities of this provider are stored under.
+func (p *Provider) Issuer() string {
+	return strings.TrimSuffix(p.config.IssuerURL, "/")
+}
+
+// NewCodeVerifier returns a random PKCE code verifier.
+func NewCodeVerifier() (string, error) {
+	return utils.NewToken()
+}
+
+// CodeChallenge returns the S256 PKCE code challenge of a verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL of the provider's login page for the given state, nonce and PKCE code verifier. With
+// forceLogin, the provider is asked to have the user enter their credentials again even if they are still logged in.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string, forceLogin bool) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if forceLogin {
+		params.Set("prompt", "login")
+		params.Set("max_age", "0")
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the claims of the verified ID token.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"client_secret": {p.config.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+
+	if tokens.IDToken == "" {
+		return nil, errors.New("oidc: token response has no ID token")
+	}
+
+	return p.verify(ctx, tokens.IDToken, nonce)
+}
+
+// verify checks the signature, issuer, audience, expiry and nonce of an ID token.
+func (p *Provider) verify(ctx context.Context, idToken, nonce string) (*Claims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	_, err = jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid ID token: %w", err)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New("oidc: ID token nonce does not match")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("oidc: ID token has no subject")
+	}
+
+	return &claims, nil
+}
+
+// discover fetches and caches the provider's discovery document.
+func (p *Provider) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery discoveryDocument
+	if err := p.getJSON(ctx, p.Issuer()+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+
+	// The issuer must match exactly so ID tokens of another issuer cannot be accepted
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer() {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", discovery.Issuer, p.Issuer())
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// key returns the signing key with the given ID, fetching the key set again when the provider rotated its keys.
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	jwksURI := p.discovery.JWKSURI
+	p.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	key, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+// getJSON fetches a JSON document from the provider.
+func (p *Provider) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s returned status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,157 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockProvider is a local OpenID Connect provider that issues an ID token for every code it receives.
+type mockProvider struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string // Code challenge sent to the authorization endpoint
+	nonce     string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	mock := &mockProvider{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 mock.server.URL,
+			"authorization_endpoint": mock.server.URL + "/authorize",
+			"token_endpoint":         mock.server.URL + "/token",
+			"jwks_uri":               mock.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "test-key",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		// The verifier must match the challenge sent with the authorization request
+		if CodeChallenge(r.PostForm.Get("code_verifier")) != mock.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": mock.idToken(t, "test-client", time.Hour)})
+	})
+
+	mock.server = httptest.NewServer(mux)
+	t.Cleanup(mock.server.Close)
+
+	return mock
+}
+
+// idToken signs an ID token for the test user.
+func (m *mockProvider) idToken(t *testing.T, audience string, expiresIn time.Duration) string {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.server.URL,
+			Subject:   "user-123",
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		},
+		Nonce:         m.nonce,
+		Email:         "test@example.com",
+		EmailVerified: true,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+
+	signed, err := token.SignedString(m.key)
+	assert.Nil(t, err)
+
+	return signed
+}
+
+func newTestProvider(t *testing.T, mock *mockProvider) *Provider {
+	provider, err := NewProvider(Config{
+		IssuerURL:    mock.server.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		RedirectURL:  "http://localhost/callback",
+		HTTPClient:   mock.server.Client(),
+	})
+	assert.Nil(t, err)
+
+	return provider
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock)
+
+	verifier, err := NewCodeVerifier()
+	assert.Nil(t, err)
+
+	authURL, err := provider.AuthCodeURL(context.TODO(), "state", "nonce", verifier, false)
+	assert.Nil(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+	assert.Equal(t, "state", parsed.Query().Get("state"))
+	assert.Empty(t, parsed.Query().Get("prompt"))
+
+	mock.challenge = parsed.Query().Get("code_challenge")
+	mock.nonce = parsed.Query().Get("nonce")
+
+	claims, err := provider.Exchange(context.TODO(), "code", verifier, "nonce")
+	assert.Nil(t, err)
+	assert.Equal(t, "user-123", claims.Subject)
+	assert.Equal(t, "test@example.com", claims.Email)
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock)
+	mock.challenge = CodeChallenge("expected-verifier")
+
+	_, err := provider.Exchange(context.TODO(), "code", "other-verifier", "nonce")
+	assert.NotNil(t, err)
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock)
+	mock.nonce = "nonce"
+
+	_, err := provider.verify(context.TODO(), mock.idToken(t, "other-client", time.Hour), "nonce")
+	assert.NotNil(t, err, "token for another client")
+
+	_, err = provider.verify(context.TODO(), mock.idToken(t, "test-client", -time.Minute), "nonce")
+	assert.NotNil(t, err, "expired token")
+
+	_, err = provider.verify(context.TODO(), mock.idToken(t, "test-client", time.Hour), "other-nonce")
+	assert.NotNil(t, err, "replayed token")
+
+	_, err = provider.verify(context.TODO(), mock.idToken(t, "test-client", time.Hour), "nonce")
+	assert.Nil(t, err)
+}
@@ -3,19 +3,23 @@ package routes
 import (
 	"infy/controllers"
 	"infy/middleware"
+	"infy/models"
+	"infy/tmdb"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AdminRoutes defines routes that are only accessible by users with administrative privileges.
-func AdminRoutes(r *gin.Engine) {
+// AdminRoutes defines routes that are only accessible by staff whose role grants the permission of the route.
+func AdminRoutes(r *gin.Engine, client *tmdb.Client) {
 	admin := r.Group("/admin")
-	admin.Use(middleware.Authorized())      // Requires authorization token
-	admin.Use(middleware.AdminAuthorized()) // Requires admin-level access
+	admin.Use(middleware.Authorized())                    // Requires authorization token
+	admin.Use(middleware.RequireScope(models.ScopeAdmin)) // Requires the admin scope for API tokens
 	{
-		admin.GET("/users", controllers.GetUsers)                          // Retrieves all users
-		admin.PUT("/users/:id", controllers.ToggleAdminStatus)             // Toggles admin status of a user
-		admin.GET("/reports/posts", controllers.GetReportedPosts)          // Retrieves reported posts
-		admin.DELETE("/reports/posts/:id", controllers.DeleteReportedPost) // Deletes a reported post
+		admin.GET("/users", middleware.RequirePermission(models.PermUsersView), controllers.GetUsers)                                                         // Retrieves all users
+		admin.PUT("/users/:id/role", middleware.RequirePermission(models.PermUsersManageRoles), controllers.SetUserRole)                                      // Changes the role of a user
+		admin.DELETE("/users/:id/lockout", middleware.RequirePermission(models.PermUsersUnlock), controllers.UnlockUser)                                      // Clears the failed logins and lockout of a user
+		admin.GET("/reports/posts", middleware.RequirePermission(models.PermReportsReview), controllers.GetReportedPosts)                                     // Retrieves reported posts
+		admin.DELETE("/reports/posts/:id", middleware.RequirePermission(models.PermReportsReview, models.PermPostsDeleteAny), controllers.DeleteReportedPost) // Deletes a reported post
+		admin.GET("/cache/tmdb", middleware.RequirePermission(models.PermCacheView), controllers.GetTMDBCacheStats(client))                                   // Retrieves TMDB cache hit/miss counters
 	}
 }
@@ -2,18 +2,62 @@ package routes
 
 import (
 	"infy/controllers"
+	"infy/mailer"
 	"infy/middleware"
+	"infy/oidc"
+	"infy/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AuthRoutes sets up the authentication routes for the application.
-func AuthRoutes(r *gin.Engine) {
+func AuthRoutes(r *gin.Engine, mail mailer.Mailer, provider *oidc.Provider, policy *validation.PasswordPolicy) {
 	auth := r.Group("/auth")
 	{
-		auth.POST("/login", controllers.Login)                            // Handles user login
-		auth.POST("/signup", controllers.Signup)                          // Handles user registration
-		auth.GET("/user", middleware.Authorized(), controllers.User)      // Retrieves the logged-in user's profile
-		auth.POST("/logout", middleware.Authorized(), controllers.Logout) // Handles user logout
+		auth.POST("/login", controllers.Login)                       // Handles user login
+		auth.POST("/login/2fa", controllers.LoginTwoFactor)          // Completes a login with a two-factor or recovery code
+		auth.POST("/signup", controllers.Signup(mail, policy))       // Handles user registration
+		auth.GET("/user", middleware.Authorized(), controllers.User) // Retrieves the logged-in user's profile
+		auth.POST("/logout", controllers.Logout)                     // Handles user logout and revokes the session
+		auth.POST("/refresh", controllers.Refresh)                   // Issues a new access token and rotates the refresh token
+
+		auth.POST("/verify-email", controllers.VerifyEmail)                                                   // Verifies the email address with the emailed token
+		auth.POST("/verify-email/resend", middleware.Authorized(), controllers.ResendVerificationEmail(mail)) // Sends a new verification email
+		auth.POST("/forgot-password", controllers.ForgotPassword(mail))                                       // Emails a password reset link
+		auth.POST("/reset-password", controllers.ResetPassword(policy))                                       // Sets a new password with the emailed token
+
+		// OpenID Connect login is only available when a provider is configured
+		if provider != nil {
+			auth.GET("/oidc/login", controllers.OIDCLogin(provider))                                                                 // Redirects to the provider to log in or sign up
+			auth.GET("/oidc/link", middleware.Authorized(), middleware.RequireSession(), controllers.OIDCLink(provider))             // Redirects to the provider to link an identity
+			auth.GET("/oidc/reauth", middleware.Authorized(), middleware.RequireSession(), controllers.OIDCReauthenticate(provider)) // Logs in again to confirm a change to an account without a password
+			auth.GET("/oidc/callback", controllers.OIDCCallback(provider))                                                           // Completes the login or linking
+			auth.POST("/oidc/signup", controllers.OIDCSignup)                                                                        // Creates the account of a new identity with its date of birth
+		}
+
+		twoFactor := auth.Group("/2fa")
+		twoFactor.Use(middleware.Authorized(), middleware.RequireSession())
+		{
+			twoFactor.POST("/enroll", controllers.EnrollTwoFactor)                 // Starts enrollment and returns the otpauth URI
+			twoFactor.POST("/confirm", controllers.ConfirmTwoFactor)               // Enables 2FA with a first code and returns recovery codes
+			twoFactor.POST("/disable", controllers.DisableTwoFactor)               // Disables 2FA after checking the password and a code
+			twoFactor.POST("/recovery-codes", controllers.RegenerateRecoveryCodes) // Replaces the recovery codes
+		}
+
+		tokens := auth.Group("/tokens")
+		tokens.Use(middleware.Authorized(), middleware.RequireSession()) // Tokens cannot be used to create more tokens
+		{
+			tokens.GET("", controllers.GetAPITokens)          // Lists the user's personal access tokens
+			tokens.POST("", controllers.CreateAPIToken)       // Creates a personal access token with scopes and expiry
+			tokens.DELETE("/:id", controllers.RevokeAPIToken) // Revokes a personal access token
+		}
+
+		sessions := auth.Group("/sessions")
+		sessions.Use(middleware.Authorized(), middleware.RequireSession())
+		{
+			sessions.GET("", controllers.GetSessions)            // Lists the user's active sessions by device
+			sessions.DELETE("", controllers.RevokeOtherSessions) // Revokes every session except the current one
+			sessions.DELETE("/:id", controllers.RevokeSession)   // Revokes a single session
+		}
 	}
 }
@@ -3,18 +3,23 @@ package routes
 import (
 	"infy/controllers"
 	"infy/middleware"
+	"infy/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 // CommentRoutes sets up routes for comment-related actions.
-func CommentRoutes(r *gin.Engine) {
+func CommentRoutes(r *gin.Engine, reactions *models.ReactionSet) {
 	comment := r.Group("/comments")
 	{
-		comment.POST("/:commentId/like", controllers.LikeComment)                  // Likes a comment
-		comment.POST("/:commentId/dislike", controllers.DislikeComment)            // Dislikes a comment
-		comment.POST("/", middleware.Authorized(), controllers.CreateComment)      // Creates a new comment
-		comment.PUT("/:id", middleware.Authorized(), controllers.UpdateComment)    // Updates an existing comment
-		comment.DELETE("/:id", middleware.Authorized(), controllers.DeleteComment) // Deletes an existing comment
+		comment.POST("/:id/like", middleware.Authorized(), controllers.LikeComment(reactions))       // Likes a comment, or takes the like back
+		comment.POST("/:id/dislike", middleware.Authorized(), controllers.DislikeComment(reactions)) // Dislikes a comment, or takes the dislike back
+		comment.GET("/:id/replies", controllers.GetCommentReplies)                                   // Retrieves a page of the replies to a comment
+		comment.POST("/", middleware.Authorized(), controllers.CreateComment)                        // Creates a new comment
+		comment.PUT("/:id", middleware.Authorized(), controllers.UpdateComment)                      // Updates an existing comment
+		comment.DELETE("/:id", middleware.Authorized(), controllers.DeleteComment)                   // Deletes an existing comment
+
+		comment.POST("/:id/reactions", middleware.Authorized(), controllers.ReactToComment(reactions)) // Reacts to a comment
+		comment.DELETE("/:id/reactions", middleware.Authorized(), controllers.RemoveCommentReaction)   // Removes the reaction to a comment
 	}
 }
@@ -3,34 +3,41 @@ package routes
 import (
 	"infy/controllers"
 	"infy/middleware"
+	"infy/models"
+	"infy/tmdb"
 
 	"github.com/gin-gonic/gin"
 )
 
 // MovieRoutes sets up routes related to movies.
-func MovieRoutes(r *gin.Engine) {
+func MovieRoutes(r *gin.Engine, client *tmdb.Client) {
 	movies := r.Group("/movies")
 	{
-		movies.GET("/:movieID", controllers.GetMovieDetails)          // Retrieves details for a specific movie
-		movies.GET("/:movieID/cast", controllers.GetMovieCast)        // Retrieves cast information for a specific movie
-		movies.GET("/:movieID/reviews", controllers.GetMovieReviews)  // Retrieves reviews for a specific movie
-		movies.GET("/:movieID/similar", controllers.GetSimilarMovies) // Retrieves movies similar to a specific movie
+		movies.GET("/:movieID", controllers.GetMovieDetails(client))                  // Retrieves details for a specific movie
+		movies.GET("/:movieID/cast", controllers.GetMovieCast(client))                // Retrieves cast information for a specific movie
+		movies.GET("/:movieID/reviews", controllers.GetMovieReviews(client))          // Retrieves reviews for a specific movie
+		movies.GET("/:movieID/similar", controllers.GetSimilarMovies(client))         // Retrieves movies similar to a specific movie
+		movies.GET("/:movieID/providers", controllers.GetMovieWatchProviders(client)) // Retrieves where a specific movie can be watched in a region
 
-		movies.GET("/:movieID/polls", controllers.GetPollsByMovieID)                                  // Retrieves polls related to a specific movie
-		movies.POST("/:movieID/polls", middleware.Authorized(), controllers.CreatePoll)               // Creates a poll related to a specific movie
-		movies.POST("/:movieID/polls/:pollID/vote", middleware.Authorized(), controllers.AddPollVote) // Adds a vote to a specific poll
+		movies.GET("/:movieID/polls", controllers.GetPolls(models.MediaTypeMovie))                             // Retrieves polls related to a specific movie
+		movies.POST("/:movieID/polls", middleware.Authorized(), controllers.CreatePoll(models.MediaTypeMovie)) // Creates a poll related to a specific movie
+		movies.POST("/:movieID/polls/:pollID/vote", middleware.Authorized(), controllers.AddPollVote)          // Adds a vote to a specific poll
 
-		movies.GET("/search", controllers.SearchMovies)                    // Searches for movies based on a query
-		movies.GET("/trending/:timeWindow", controllers.GetTrendingMovies) // Retrieves trending movies within a specified time window
+		movies.GET("/genres", controllers.GetMovieGenres(client))                  // Retrieves the list of movie genres
+		movies.GET("/discover", controllers.DiscoverMovies(client))                // Finds movies matching genre, year, rating, runtime and language filters
+		movies.GET("/providers", controllers.GetMovieProviders(client))            // Retrieves the streaming services available in a region
+		movies.GET("/search", controllers.SearchMovies(client))                    // Searches for movies based on a query
+		movies.GET("/trending/:timeWindow", controllers.GetTrendingMovies(client)) // Retrieves trending movies within a specified time window
 
 		// New actor details route + movie credits route + movie actorID finder + Movie Trailers
-		movies.GET("/actor/:actorID", controllers.GetActorDetails)
-		movies.GET("/actor/:actorID/movies", controllers.GetActorMovieCredits)
-		movies.GET("/:movieID/trailers", controllers.GetMovieTrailers)
+		movies.GET("/actor/:actorID", controllers.GetActorDetails(client))
+		movies.GET("/actor/:actorID/movies", controllers.GetActorMovieCredits(client))
+		movies.GET("/:movieID/trailers", controllers.GetMovieTrailers(client))
 	}
 
 	people := r.Group("/people")
 	{
-		people.GET("/search", controllers.SearchPeople) // Retrieves details for a specific person
+		people.GET("/search", controllers.SearchPeople(client))                // Searches for people by name
+		people.GET("/:personID/credits", controllers.GetPersonCredits(client)) // Retrieves a person's cast and crew credits, newest first
 	}
 }
@@ -3,25 +3,32 @@ package routes
 import (
 	"infy/controllers"
 	"infy/middleware"
+	"infy/models"
+	"infy/tmdb"
 
 	"github.com/gin-gonic/gin"
 )
 
 // PostRoutes sets up routes related to posts.
-func PostRoutes(r *gin.Engine) {
+func PostRoutes(r *gin.Engine, client *tmdb.Client, reactions *models.ReactionSet) {
 	post := r.Group("/posts")
 	{
-		post.GET("/", controllers.GetPosts)                                  // Retrieves all posts
-		post.GET("/:id", controllers.GetPost)                                // Retrieves a specific post
-		post.POST("/", middleware.Authorized(), controllers.CreatePost)      // Creates a new post
-		post.PUT("/:id", middleware.Authorized(), controllers.UpdatePost)    // Updates an existing post
-		post.DELETE("/:id", middleware.Authorized(), controllers.DeletePost) // Deletes an existing post
+		post.GET("/", controllers.GetPosts)                                     // Retrieves all posts
+		post.GET("/:id", controllers.GetPost)                                   // Retrieves a specific post
+		post.GET("/:id/comments", controllers.GetPostComments)                  // Retrieves a page of the comments on a post
+		post.POST("/", middleware.Authorized(), controllers.CreatePost(client)) // Creates a new post
+		post.PUT("/:id", middleware.Authorized(), controllers.UpdatePost)       // Updates an existing post
+		post.DELETE("/:id", middleware.Authorized(), controllers.DeletePost)    // Deletes an existing post
 
-		post.POST("/:id/like", middleware.Authorized(), controllers.LikePost)       // Likes a post
-		post.POST("/:id/dislike", middleware.Authorized(), controllers.DislikePost) // Dislikes a post
-		post.GET("/:id/report", middleware.Authorized(), controllers.ReportPost)    // Reports a post
+		post.POST("/:id/like", middleware.Authorized(), controllers.LikePost(reactions))         // Likes a post
+		post.POST("/:id/dislike", middleware.Authorized(), controllers.DislikePost(reactions))   // Dislikes a post
+		post.GET("/:id/reactions", controllers.GetPostReactions(reactions))                      // Lists who reacted to a post
+		post.POST("/:id/reactions", middleware.Authorized(), controllers.ReactToPost(reactions)) // Reacts to a post
+		post.DELETE("/:id/reactions", middleware.Authorized(), controllers.RemovePostReaction)   // Removes the reaction to a post
+		post.GET("/:id/report", middleware.Authorized(), controllers.ReportPost)                 // Reports a post
 
-		post.GET("/user/:userID", controllers.GetUserPosts)        // Retrieves posts by a specific user
-		post.GET("/movie/:movieID", controllers.GetPostsByMovieID) // Retrieves posts related to a specific movie
+		post.GET("/user/:userID", controllers.GetUserPosts)                             // Retrieves posts by a specific user
+		post.GET("/movie/:movieID", controllers.GetPostsByMedia(models.MediaTypeMovie)) // Retrieves posts related to a specific movie
+		post.GET("/tv/:tvID", controllers.GetPostsByMedia(models.MediaTypeTV))          // Retrieves posts related to a specific TV show
 	}
 }
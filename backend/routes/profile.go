@@ -2,23 +2,36 @@ package routes
 
 import (
 	"infy/controllers"
+	"infy/mailer"
 	"infy/middleware"
+	"infy/tmdb"
+	"infy/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ProfileRoutes sets up routes for user profiles and related functionalities.
-func ProfileRoutes(r *gin.Engine) {
+func ProfileRoutes(r *gin.Engine, client *tmdb.Client, mail mailer.Mailer, policy *validation.PasswordPolicy) {
 	profile := r.Group("/profile")
 	{
 		userProfile := profile.Group("/user")
 		userProfile.Use(middleware.Authorized())
 		{
-			userProfile.GET("/", controllers.GetUserProfile)       // Retrieves the logged-in user's profile
-			userProfile.POST("/avatar", controllers.AddUserAvatar) // Adds an avatar to the user's profile
+			userProfile.GET("/", controllers.GetUserProfile)                                              // Retrieves the logged-in user's profile
+			userProfile.PATCH("", controllers.UpdateUserProfile)                                          // Updates the user's name and favorite genres
+			userProfile.DELETE("", middleware.RequireSession(), controllers.DeleteAccount)                // Schedules the user's account for deletion
+			userProfile.PUT("/email", middleware.RequireSession(), controllers.ChangeEmail(mail))         // Changes the user's email after confirming the password
+			userProfile.PUT("/password", middleware.RequireSession(), controllers.ChangePassword(policy)) // Changes the user's password and logs out other sessions
+			userProfile.POST("/avatar", controllers.AddUserAvatar)                                        // Adds an avatar to the user's profile
+			userProfile.PUT("/streaming", controllers.UpdateStreamingSettings)                            // Sets the user's region and subscribed streaming services
+			userProfile.GET("/export", middleware.RequireSession(), controllers.GetDataExport)            // Starts or returns the user's personal data export
+			userProfile.GET("/login-attempts", controllers.GetLoginAttempts)                              // Lists the failed logins of the user's account
+			userProfile.GET("/identities", controllers.GetIdentities)                                     // Lists the linked OpenID Connect accounts
+			userProfile.DELETE("/identities", middleware.RequireSession(), controllers.UnlinkIdentity)    // Unlinks the OpenID Connect account of ?issuer=
 		}
 
-		profile.GET("/:username", controllers.GetProfile) // Retrieves a user's profile by username
+		profile.GET("/user/export/download", controllers.DownloadDataExport) // Downloads a data export, the link's token authorizes it
+		profile.GET("/:username", controllers.GetProfile)                    // Retrieves a user's profile by username
 
 		profile.POST("/follow/:id", middleware.Authorized(), controllers.Follow)       // Follows another user
 		profile.DELETE("/unfollow/:id", middleware.Authorized(), controllers.Unfollow) // Unfollows another user
@@ -26,15 +39,17 @@ func ProfileRoutes(r *gin.Engine) {
 		movies := profile.Group("/movies")
 		movies.Use(middleware.Authorized())
 		{
-			movies.POST("/add/watched", controllers.AddMovieToWatched)                             // Adds a movie to the user's watched list
-			movies.POST("/add/watchlist", controllers.AddMovieToWatchlist)                         // Adds a movie to the user's watchlist
+			movies.GET("/watched", controllers.GetWatched(client))                                 // Retrieves the movies on the user's watched list
+			movies.GET("/watchlist", controllers.GetWatchlist(client))                             // Retrieves the movies on the user's watchlist
+			movies.POST("/add/watched", controllers.AddMovieToWatched(client))                     // Adds a movie to the user's watched list
+			movies.POST("/add/watchlist", controllers.AddMovieToWatchlist(client))                 // Adds a movie to the user's watchlist
 			movies.DELETE("/watched/:id", controllers.RemoveMovieFromWatched)                      // Removes a movie from the watched list
 			movies.DELETE("/watchlist/:id", controllers.RemoveMovieFromWatchlist)                  // Removes a movie from the watchlist
 			movies.GET("/:movieID/watchedByFollowed", controllers.GetFollowedUsersWhoWatchedMovie) // Gets followed users who watched a specific movie
-			movies.GET("/watched/recommendations", controllers.GetRecommendationsFromWatched)
-			movies.GET("/watchlist/recommendations", controllers.GetRecommendationsFromWatchList)
-			movies.GET("/following/watched", controllers.GetRecommendationsFromFollowing)
-			movies.GET("/followers/watched", controllers.GetRecommendationsFromFollowers)
+			movies.GET("/watched/recommendations", controllers.GetRecommendationsFromWatched(client))
+			movies.GET("/watchlist/recommendations", controllers.GetRecommendationsFromWatchList(client))
+			movies.GET("/following/watched", controllers.GetRecommendationsFromFollowing(client))
+			movies.GET("/followers/watched", controllers.GetRecommendationsFromFollowers(client))
 		}
 	}
 }
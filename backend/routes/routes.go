@@ -1,18 +1,31 @@
 package routes
 
 import (
+	"infy/mailer"
+	"infy/models"
+	"infy/oidc"
+	"infy/tmdb"
+	"infy/utils"
+	"infy/validation"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// InitRoutes initializes all the route groups and settings for the application.
-func InitRoutes() *gin.Engine {
+// InitRoutes initializes all the route groups and settings for the application. Client IPs are only read from
+// X-Forwarded-For when the request comes from one of the proxies in the comma separated TRUSTED_PROXIES, so clients
+// cannot pick the IP used for the login lockout and sessions.
+func InitRoutes(client *tmdb.Client, mail mailer.Mailer, provider *oidc.Provider, policy *validation.PasswordPolicy, reactions *models.ReactionSet) (*gin.Engine, error) {
 	router := gin.Default()
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		return nil, err
+	}
+
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Content-Type", "Content-Length", "Accept-Encoding", "Authorization", "Cache-Control"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
@@ -23,12 +36,25 @@ func InitRoutes() *gin.Engine {
 	router.Static("/avatars", "./uploads/avatars")
 
 	// Register the route groups
-	AuthRoutes(router)
-	PostRoutes(router)
-	ProfileRoutes(router)
-	CommentRoutes(router)
-	MovieRoutes(router)
-	AdminRoutes(router)
-
-	return router
+	AuthRoutes(router, mail, provider, policy)
+	PostRoutes(router, client, reactions)
+	ProfileRoutes(router, client, mail, policy)
+	CommentRoutes(router, reactions)
+	MovieRoutes(router, client)
+	TVRoutes(router, client)
+	AdminRoutes(router, client)
+
+	return router, nil
+}
+
+// trustedProxies returns the IPs and CIDR ranges of the proxies in TRUSTED_PROXIES, or nil to trust none
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(utils.GetEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
 }
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"infy/controllers"
+	"infy/middleware"
+	"infy/models"
+	"infy/tmdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TVRoutes sets up routes related to TV shows, mirroring the movie routes.
+func TVRoutes(r *gin.Engine, client *tmdb.Client) {
+	tv := r.Group("/tv")
+	{
+		tv.GET("/:tvID", controllers.GetTVDetails(client))           // Retrieves details for a specific TV show
+		tv.GET("/:tvID/cast", controllers.GetTVCast(client))         // Retrieves cast information for a specific TV show
+		tv.GET("/:tvID/similar", controllers.GetSimilarTV(client))   // Retrieves TV shows similar to a specific TV show
+		tv.GET("/:tvID/trailers", controllers.GetTVTrailers(client)) // Retrieves trailers for a specific TV show
+
+		tv.GET("/:tvID/seasons/:seasonNumber", controllers.GetTVSeason(client))                          // Retrieves a season and its episodes
+		tv.GET("/:tvID/seasons/:seasonNumber/episodes/:episodeNumber", controllers.GetTVEpisode(client)) // Retrieves a single episode
+
+		tv.GET("/:tvID/polls", controllers.GetPolls(models.MediaTypeTV))                             // Retrieves polls related to a specific TV show
+		tv.POST("/:tvID/polls", middleware.Authorized(), controllers.CreatePoll(models.MediaTypeTV)) // Creates a poll related to a specific TV show
+		tv.POST("/:tvID/polls/:pollID/vote", middleware.Authorized(), controllers.AddPollVote)       // Adds a vote to a specific poll
+	}
+}
@@ -0,0 +1,190 @@
+package tmdb
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Endpoint names used to look up the cache TTL of a request.
+const (
+	EndpointSearch        = "search"
+	EndpointDetails       = "details"
+	EndpointTrending      = "trending"
+	EndpointCredits       = "credits"
+	EndpointReviews       = "reviews"
+	EndpointSimilar       = "similar"
+	EndpointVideos        = "videos"
+	EndpointPerson        = "person"
+	EndpointPersonMovie   = "person_movies"
+	EndpointPersonCredits = "person_credits"
+	EndpointGenres        = "genres"
+	EndpointDiscover      = "discover"
+	EndpointTV            = "tv"
+	EndpointTVSeason      = "tv_season"
+	EndpointProviders     = "providers"
+)
+
+// DefaultCacheTTLs are the TTLs used for endpoints that are not configured explicitly.
+var DefaultCacheTTLs = map[string]time.Duration{
+	EndpointSearch:        15 * time.Minute,
+	EndpointDetails:       24 * time.Hour,
+	EndpointTrending:      time.Hour,
+	EndpointCredits:       24 * time.Hour,
+	EndpointReviews:       6 * time.Hour,
+	EndpointSimilar:       24 * time.Hour,
+	EndpointVideos:        24 * time.Hour,
+	EndpointPerson:        24 * time.Hour,
+	EndpointPersonMovie:   24 * time.Hour,
+	EndpointPersonCredits: 24 * time.Hour,
+	EndpointGenres:        7 * 24 * time.Hour,
+	EndpointDiscover:      time.Hour,
+	EndpointTV:            24 * time.Hour,
+	EndpointTVSeason:      24 * time.Hour,
+	EndpointProviders:     12 * time.Hour,
+}
+
+// DefaultCacheSize is the number of entries kept by the default in-memory cache.
+const DefaultCacheSize = 1000
+
+// Cache stores raw TMDB response bodies by request key.
+type Cache interface {
+	// Get returns the cached value for the key and whether it was found and not expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value for the key until the TTL expires.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheStats holds the hit and miss counters of the client cache.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// cacheCounters keeps the hit and miss counters safe for concurrent use.
+type cacheCounters struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// LRUCache is an in-memory Cache that evicts the least recently used entry when full.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUCache creates a new in-memory cache holding at most capacity entries.
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+
+	return &LRUCache{capacity: capacity, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Get returns the cached value for the key if it exists and has not expired.
+func (c *LRUCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set stores the value for the key, evicting the least recently used entry if the cache is full.
+func (c *LRUCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+
+	return nil
+}
+
+// MongoCache is a Cache backed by a MongoDB collection so entries are shared between instances.
+type MongoCache struct {
+	Collection *mongo.Collection
+}
+
+type mongoCacheEntry struct {
+	Key       string    `bson:"_id"`
+	Value     []byte    `bson:"value"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// EnsureIndexes creates the TTL index that lets MongoDB remove expired entries.
+func (c *MongoCache) EnsureIndexes(ctx context.Context) error {
+	_, err := c.Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	return err
+}
+
+// Get returns the cached value for the key if it exists and has not expired.
+func (c *MongoCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var entry mongoCacheEntry
+
+	// MongoDB only removes expired documents periodically, so filter them out here as well
+	filter := bson.M{"_id": key, "expires_at": bson.M{"$gt": time.Now()}}
+	err := c.Collection.FindOne(ctx, filter).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return entry.Value, true, nil
+}
+
+// Set stores the value for the key until the TTL expires.
+func (c *MongoCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := mongoCacheEntry{Key: key, Value: value, ExpiresAt: time.Now().Add(ttl)}
+	opts := options.Replace().SetUpsert(true)
+	_, err := c.Collection.ReplaceOne(ctx, bson.M{"_id": key}, entry, opts)
+
+	return err
+}
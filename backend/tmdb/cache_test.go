@@ -0,0 +1,92 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewLRUCache(2)
+	ctx := context.TODO()
+
+	cache.Set(ctx, "a", []byte("a"), time.Minute)
+	cache.Set(ctx, "b", []byte("b"), time.Minute)
+
+	// Touch "a" so "b" becomes the least recently used entry
+	_, found, _ := cache.Get(ctx, "a")
+	assert.True(t, found)
+
+	cache.Set(ctx, "c", []byte("c"), time.Minute)
+
+	_, found, _ = cache.Get(ctx, "b")
+	assert.False(t, found)
+
+	value, found, _ := cache.Get(ctx, "a")
+	assert.True(t, found)
+	assert.Equal(t, []byte("a"), value)
+}
+
+func TestLRUCacheExpiresEntries(t *testing.T) {
+	cache := NewLRUCache(2)
+	ctx := context.TODO()
+
+	cache.Set(ctx, "a", []byte("a"), -time.Second)
+
+	_, found, _ := cache.Get(ctx, "a")
+	assert.False(t, found)
+}
+
+func TestClientCachesResponses(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"id": 550, "title": "Fight Club"}`))
+	})
+
+	for i := 0; i < 3; i++ {
+		details, err := client.GetMovieDetails(context.TODO(), "550")
+		assert.Nil(t, err)
+		assert.Equal(t, "Fight Club", details.Title)
+	}
+
+	// Validating the movie shares the cache entry of the details
+	valid, err := client.IsValidMovieID(context.TODO(), "550")
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 1}, client.CacheStats())
+}
+
+func TestClientCoalescesConcurrentRequests(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Write([]byte(`{"results": []}`))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetTrendingMovies(context.TODO(), "day")
+			assert.Nil(t, err)
+		}()
+	}
+
+	// Give the goroutines time to join the in-flight request before it completes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), requests.Load())
+}
@@ -0,0 +1,223 @@
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"infy/models"
+)
+
+// DefaultCatalogCastLimit is how many cast members are kept in catalog records when not configured.
+const DefaultCatalogCastLimit = 10
+
+// Catalog stores the normalized movie records fetched through the client.
+type Catalog interface {
+	UpsertMovie(ctx context.Context, movie *models.MovieRecord) error
+	FindMoviesByIDs(ctx context.Context, ids []int) ([]*models.MovieRecord, error)
+	FindStaleMovieIDs(ctx context.Context, updatedBefore time.Time, limit int64) ([]int, error)
+}
+
+// movieDetailsWithCredits is the movie details response with the credits appended to it.
+type movieDetailsWithCredits struct {
+	MovieDetails
+	Credits CastResponse `json:"credits"`
+}
+
+// movieDetailsParams requests the credits along with the details so catalog records get their cast without a second request.
+func movieDetailsParams() url.Values {
+	return url.Values{"append_to_response": {"credits"}}
+}
+
+// newMovieRecord normalizes the TMDB movie details into a catalog record keeping the top castLimit cast members.
+func newMovieRecord(details *movieDetailsWithCredits, castLimit int) *models.MovieRecord {
+	record := &models.MovieRecord{
+		ID:           details.ID,
+		Title:        details.Title,
+		ReleaseDate:  details.ReleaseDate,
+		Tagline:      details.Tagline,
+		Overview:     details.Overview,
+		Genres:       details.Genres,
+		Runtime:      details.Runtime,
+		Rating:       details.VoteAverage,
+		PosterPath:   details.PosterPath,
+		BackdropPath: details.BackdropPath,
+		Cast:         []models.CastMember{},
+		UpdatedAt:    time.Now(),
+	}
+
+	// Release dates are formatted as YYYY-MM-DD but may be empty for unreleased movies
+	if len(details.ReleaseDate) >= 4 {
+		record.Year, _ = strconv.Atoi(details.ReleaseDate[:4])
+	}
+
+	if record.Genres == nil {
+		record.Genres = []models.Genre{}
+	}
+
+	for i, member := range details.Credits.Cast {
+		if i >= castLimit {
+			break
+		}
+
+		record.Cast = append(record.Cast, models.CastMember{
+			ID:          member.ID,
+			Name:        member.Name,
+			Character:   member.Character,
+			ProfilePath: member.ProfilePath,
+		})
+	}
+
+	return record
+}
+
+// saveToCatalog upserts the movie into the catalog, logging failures so they never fail the request.
+func (c *Client) saveToCatalog(ctx context.Context, details *movieDetailsWithCredits) {
+	if c.catalog == nil {
+		return
+	}
+
+	if err := c.catalog.UpsertMovie(ctx, newMovieRecord(details, c.catalogCastLimit)); err != nil {
+		log.Println(err)
+	}
+}
+
+// inCatalog returns whether the catalog has a record of the movie. Without a catalog there is nothing to save, and
+// lookup failures are logged and treated as missing so the record is written again.
+func (c *Client) inCatalog(ctx context.Context, movieID int) bool {
+	if c.catalog == nil {
+		return true
+	}
+
+	records, err := c.catalog.FindMoviesByIDs(ctx, []int{movieID})
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	return len(records) > 0
+}
+
+// CatalogMovies returns the catalog records of the given movie IDs in the same order,
+// fetching the movies that are not in the catalog yet from TMDB. Invalid and unknown IDs are skipped.
+func (c *Client) CatalogMovies(ctx context.Context, movieIDs []string) ([]*models.MovieRecord, error) {
+	ids := make([]int, 0, len(movieIDs))
+	for _, movieID := range movieIDs {
+		if id, err := strconv.Atoi(movieID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	found := make(map[int]*models.MovieRecord, len(ids))
+	if c.catalog != nil {
+		records, err := c.catalog.FindMoviesByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			found[record.ID] = record
+		}
+	}
+
+	movies := make([]*models.MovieRecord, 0, len(ids))
+	for _, id := range ids {
+		record, ok := found[id]
+		if !ok {
+			details, err := c.fetchMovieWithCredits(ctx, strconv.Itoa(id), false)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			record = newMovieRecord(details, c.catalogCastLimit)
+		}
+
+		movies = append(movies, record)
+	}
+
+	return movies, nil
+}
+
+// RefreshStaleMovies fetches up to limit catalog records last updated before the given time again from TMDB.
+// It returns the number of refreshed records.
+func (c *Client) RefreshStaleMovies(ctx context.Context, updatedBefore time.Time, limit int64) (int, error) {
+	if c.catalog == nil {
+		return 0, nil
+	}
+
+	ids, err := c.catalog.FindStaleMovieIDs(ctx, updatedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, id := range ids {
+		// Skip the cache, otherwise the record would be rebuilt from the same stale response
+		if _, err := c.fetchMovieWithCredits(ctx, strconv.Itoa(id), true); err != nil {
+			log.Println(err)
+			continue
+		}
+		refreshed++
+	}
+
+	return refreshed, nil
+}
+
+// RunCatalogRefresh refreshes records older than maxAge in batches every interval until the context is cancelled.
+func (c *Client) RunCatalogRefresh(ctx context.Context, interval, maxAge time.Duration, batchSize int64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refreshed, err := c.RefreshStaleMovies(ctx, time.Now().Add(-maxAge), batchSize)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			if refreshed > 0 {
+				log.Printf("Refreshed %d stale movies in the catalog", refreshed)
+			}
+		}
+	}
+}
+
+// fetchMovieWithCredits fetches the movie details and credits, optionally bypassing the cache, and saves the movie to
+// the catalog if the response is fresh or the catalog has no record of it yet.
+func (c *Client) fetchMovieWithCredits(ctx context.Context, movieID string, skipCache bool) (*movieDetailsWithCredits, error) {
+	path := "/movie/" + url.PathEscape(movieID)
+
+	var resp *response
+	var err error
+	if skipCache {
+		resp, err = c.refresh(ctx, EndpointDetails, path, movieDetailsParams())
+	} else {
+		resp, err = c.fetch(ctx, EndpointDetails, path, movieDetailsParams())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var details movieDetailsWithCredits
+	if err := json.Unmarshal(resp.body, &details); err != nil {
+		return nil, err
+	}
+
+	// Cached responses, which may outlive the catalog record in the Mongo cache, are only saved if the record is missing
+	if !resp.cached || !c.inCatalog(ctx, details.ID) {
+		c.saveToCatalog(ctx, &details)
+	}
+
+	return &details, nil
+}
@@ -0,0 +1,129 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"infy/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryCatalog is a Catalog keeping records in a map for tests.
+type memoryCatalog struct {
+	movies  map[int]*models.MovieRecord
+	upserts int
+}
+
+func (m *memoryCatalog) UpsertMovie(_ context.Context, movie *models.MovieRecord) error {
+	m.movies[movie.ID] = movie
+	m.upserts++
+	return nil
+}
+
+func (m *memoryCatalog) FindMoviesByIDs(_ context.Context, ids []int) ([]*models.MovieRecord, error) {
+	var movies []*models.MovieRecord
+	for _, id := range ids {
+		if movie, ok := m.movies[id]; ok {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, nil
+}
+
+func (m *memoryCatalog) FindStaleMovieIDs(_ context.Context, updatedBefore time.Time, _ int64) ([]int, error) {
+	var ids []int
+	for id, movie := range m.movies {
+		if movie.UpdatedAt.Before(updatedBefore) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+const fightClubWithCredits = `{
+	"id": 550,
+	"title": "Fight Club",
+	"release_date": "1999-10-15",
+	"runtime": 139,
+	"genres": [{"id": 18, "name": "Drama"}],
+	"credits": {"cast": [
+		{"id": 819, "name": "Edward Norton", "character": "The Narrator"},
+		{"id": 287, "name": "Brad Pitt", "character": "Tyler Durden"}
+	]}
+}`
+
+func TestGetMovieDetailsSavesToCatalog(t *testing.T) {
+	var requests atomic.Int32
+	catalog := &memoryCatalog{movies: map[int]*models.MovieRecord{}}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.Equal(t, "credits", r.URL.Query().Get("append_to_response"))
+		w.Write([]byte(fightClubWithCredits))
+	})
+	client.catalog = catalog
+	client.catalogCastLimit = 1
+
+	client.GetMovieDetails(context.TODO(), "550")
+	client.GetMovieDetails(context.TODO(), "550")
+
+	// Cached responses are not saved again
+	assert.Equal(t, 1, catalog.upserts)
+
+	record := catalog.movies[550]
+	assert.Equal(t, 1999, record.Year)
+	assert.Equal(t, []models.Genre{{ID: 18, Name: "Drama"}}, record.Genres)
+	assert.Equal(t, []models.CastMember{{ID: 819, Name: "Edward Norton", Character: "The Narrator"}}, record.Cast)
+
+	// Movies already in the catalog are served without calling TMDB
+	movies, err := client.CatalogMovies(context.TODO(), []string{"550", "not-a-movie"})
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.MovieRecord{record}, movies)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestCachedMovieSavedWhenMissingFromCatalog(t *testing.T) {
+	var requests atomic.Int32
+	catalog := &memoryCatalog{movies: map[int]*models.MovieRecord{}}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(fightClubWithCredits))
+	})
+
+	// The response is cached before the catalog is configured, like a Mongo cache entry from an earlier run
+	valid, err := client.IsValidMovieID(context.TODO(), "550")
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	client.catalog = catalog
+	valid, err = client.IsValidMovieID(context.TODO(), "550")
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, 1, catalog.upserts)
+	assert.Equal(t, "Fight Club", catalog.movies[550].Title)
+}
+
+func TestRefreshStaleMovies(t *testing.T) {
+	var requests atomic.Int32
+	catalog := &memoryCatalog{movies: map[int]*models.MovieRecord{
+		550: {ID: 550, Title: "Old Title", UpdatedAt: time.Now().Add(-48 * time.Hour)},
+		551: {ID: 551, Title: "Fresh", UpdatedAt: time.Now()},
+	}}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(fightClubWithCredits))
+	})
+	client.catalog = catalog
+
+	refreshed, err := client.RefreshStaleMovies(context.TODO(), time.Now().Add(-24*time.Hour), 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, refreshed)
+	assert.Equal(t, "Fight Club", catalog.movies[550].Title)
+	assert.Equal(t, int32(1), requests.Load())
+}
@@ -0,0 +1,308 @@
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"infy/utils"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultBaseURL is the base URL of the public TMDB v3 API.
+const DefaultBaseURL = "https://api.themoviedb.org/3"
+
+// DefaultTimeout is used when no timeout is configured for the client.
+const DefaultTimeout = 10 * time.Second
+
+// Retry defaults used when the config leaves them unset.
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 500 * time.Millisecond
+	DefaultMaxRetryWait = 10 * time.Second
+)
+
+// Config holds the settings used to build a Client.
+type Config struct {
+	APIKey     string
+	BaseURL    string
+	Timeout    time.Duration
+	HTTPClient *http.Client
+	// Cache defaults to an in-memory LRU cache of CacheSize entries when nil.
+	Cache     Cache
+	CacheSize int
+	// CacheTTLs overrides the entries of DefaultCacheTTLs by endpoint name.
+	CacheTTLs map[string]time.Duration
+	// Catalog receives a normalized record of every movie fetched from TMDB when set.
+	Catalog Catalog
+	// CatalogCastLimit is how many top-billed cast members are kept in catalog records.
+	CatalogCastLimit int
+	// MaxRetries is how many times a rate limited or failed request is retried. Use a negative value to disable retries.
+	MaxRetries int
+	// RetryBackoff is the first wait between retries, doubled on every attempt unless TMDB sends Retry-After.
+	RetryBackoff time.Duration
+	// MaxRetryWait caps how long a single retry waits; longer Retry-After values fail with ErrRateLimited.
+	MaxRetryWait time.Duration
+}
+
+// Client talks to the TMDB API using a configurable key, base URL and HTTP client.
+type Client struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	cache      Cache
+	cacheTTLs  map[string]time.Duration
+	counters   cacheCounters
+	group      singleflight.Group
+
+	catalog          Catalog
+	catalogCastLimit int
+
+	maxRetries   int
+	retryBackoff time.Duration
+	maxRetryWait time.Duration
+}
+
+// response is the status code and body of a TMDB response shared between coalesced requests.
+type response struct {
+	status     int
+	body       []byte
+	retryAfter time.Duration
+	cached     bool
+}
+
+// ConfigFromEnv builds a Config from the TMDB_API_KEY, TMDB_BASE_URL, TMDB_TIMEOUT and TMDB_CACHE_SIZE environment variables.
+func ConfigFromEnv() (Config, error) {
+	timeout, err := time.ParseDuration(utils.GetEnv("TMDB_TIMEOUT", DefaultTimeout.String()))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cacheSize, err := strconv.Atoi(utils.GetEnv("TMDB_CACHE_SIZE", strconv.Itoa(DefaultCacheSize)))
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Config{
+		APIKey:    utils.GetEnv("TMDB_API_KEY", ""),
+		BaseURL:   utils.GetEnv("TMDB_BASE_URL", DefaultBaseURL),
+		Timeout:   timeout,
+		CacheSize: cacheSize,
+	}, nil
+}
+
+// NewClient creates a new TMDB client from the given config.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("tmdb: API key is required")
+	}
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	cache := cfg.Cache
+	if cache == nil {
+		cache = NewLRUCache(cfg.CacheSize)
+	}
+
+	cacheTTLs := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for endpoint, ttl := range DefaultCacheTTLs {
+		cacheTTLs[endpoint] = ttl
+	}
+	for endpoint, ttl := range cfg.CacheTTLs {
+		cacheTTLs[endpoint] = ttl
+	}
+
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = DefaultMaxRetries
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+
+	if cfg.CatalogCastLimit <= 0 {
+		cfg.CatalogCastLimit = DefaultCatalogCastLimit
+	}
+
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+
+	if cfg.MaxRetryWait <= 0 {
+		cfg.MaxRetryWait = DefaultMaxRetryWait
+	}
+
+	return &Client{
+		apiKey:           cfg.APIKey,
+		baseURL:          strings.TrimRight(cfg.BaseURL, "/"),
+		httpClient:       httpClient,
+		cache:            cache,
+		cacheTTLs:        cacheTTLs,
+		catalog:          cfg.Catalog,
+		catalogCastLimit: cfg.CatalogCastLimit,
+		maxRetries:       cfg.MaxRetries,
+		retryBackoff:     cfg.RetryBackoff,
+		maxRetryWait:     cfg.MaxRetryWait,
+	}, nil
+}
+
+// CacheStats returns the number of cache hits and misses since the client was created.
+func (c *Client) CacheStats() CacheStats {
+	return CacheStats{Hits: c.counters.hits.Load(), Misses: c.counters.misses.Load()}
+}
+
+// buildURL joins the path onto the base URL and adds the API key to the query parameters.
+func (c *Client) buildURL(path string, params url.Values) string {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("api_key", c.apiKey)
+
+	return c.baseURL + path + "?" + query.Encode()
+}
+
+// do sends a GET request for the given path and returns the status code and body of the response.
+func (c *Client) do(ctx context.Context, path string, params url.Values) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL(path, params), nil)
+	if err != nil {
+		return nil, newTransportError(err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, newTransportError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newTransportError(err)
+	}
+
+	return &response{status: resp.StatusCode, body: body, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}, nil
+}
+
+// doWithRetry sends the request and retries rate limited and server errors with exponential backoff,
+// waiting for as long as TMDB asks through the Retry-After header when it is sent.
+func (c *Client) doWithRetry(ctx context.Context, path string, params url.Values) (*response, error) {
+	backoff := c.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.do(ctx, path, params)
+		if err == nil && resp.status == http.StatusOK {
+			return resp, nil
+		}
+
+		// do only returns *Error values, so both paths end up with the details of the failure
+		var apiErr *Error
+		if err != nil {
+			apiErr = err.(*Error)
+		} else {
+			apiErr = newResponseError(resp)
+		}
+
+		// Transport errors have no status code and are retried like server errors
+		retryable := apiErr.StatusCode == 0 || apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+		if !retryable || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		wait := backoff
+		if apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		if wait > c.maxRetryWait {
+			return nil, apiErr
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, apiErr
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+	}
+}
+
+// fetch returns the response for the given path from the cache, or requests it from TMDB and caches it.
+// Concurrent requests for the same path and parameters share a single call to TMDB.
+// Only successful responses are cached; failures are returned as an *Error.
+func (c *Client) fetch(ctx context.Context, endpoint, path string, params url.Values) (*response, error) {
+	// The key is built before the API key is added so it never ends up in the cache
+	key := path + "?" + params.Encode()
+
+	body, found, err := c.cache.Get(ctx, key)
+	if err != nil {
+		log.Println(err)
+	}
+	if found {
+		c.counters.hits.Add(1)
+		return &response{status: http.StatusOK, body: body, cached: true}, nil
+	}
+	c.counters.misses.Add(1)
+
+	result, err, _ := c.group.Do(key, func() (interface{}, error) {
+		// Don't let one caller cancelling its request fail the others waiting on the same call
+		sharedCtx := context.WithoutCancel(ctx)
+		resp, err := c.doWithRetry(sharedCtx, path, params)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := c.cache.Set(sharedCtx, key, resp.body, c.cacheTTLs[endpoint]); err != nil {
+			log.Println(err)
+		}
+
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*response), nil
+}
+
+// refresh requests the given path from TMDB without looking at the cache and caches the new response.
+func (c *Client) refresh(ctx context.Context, endpoint, path string, params url.Values) (*response, error) {
+	resp, err := c.doWithRetry(ctx, path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.cache.Set(ctx, path+"?"+params.Encode(), resp.body, c.cacheTTLs[endpoint]); err != nil {
+		log.Println(err)
+	}
+
+	return resp, nil
+}
+
+// get fetches the given path and decodes the JSON response body into out.
+func (c *Client) get(ctx context.Context, endpoint, path string, params url.Values, out interface{}) error {
+	resp, err := c.fetch(ctx, endpoint, path, params)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(resp.body, out)
+}
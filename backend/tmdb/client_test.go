@@ -0,0 +1,143 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, HTTPClient: server.Client()})
+	assert.Nil(t, err)
+
+	return client
+}
+
+func TestNewClientRequiresAPIKey(t *testing.T) {
+	client, err := NewClient(Config{})
+
+	assert.Nil(t, client)
+	assert.NotNil(t, err)
+}
+
+func TestGetMovieDetails(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Assert the request was sent to the configured server with the configured key
+		assert.Equal(t, "/movie/550", r.URL.Path)
+		assert.Equal(t, "test-key", r.URL.Query().Get("api_key"))
+
+		w.Write([]byte(`{"id": 550, "title": "Fight Club", "runtime": 139}`))
+	})
+
+	details, err := client.GetMovieDetails(context.TODO(), "550")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 550, details.ID)
+	assert.Equal(t, "Fight Club", details.Title)
+	assert.Equal(t, 139, details.Runtime)
+}
+
+func TestIsValidMovieID(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/movie/550" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`{"id": 550}`))
+	})
+
+	valid, err := client.IsValidMovieID(context.TODO(), "550")
+	assert.Nil(t, err)
+	assert.True(t, valid)
+
+	valid, err = client.IsValidMovieID(context.TODO(), "0")
+	assert.Nil(t, err)
+	assert.False(t, valid)
+}
+
+func TestNotFoundError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status_code": 34, "status_message": "The resource you requested could not be found."}`))
+	})
+
+	details, err := client.GetMovieDetails(context.TODO(), "0")
+
+	assert.Nil(t, details)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var apiErr *Error
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "The resource you requested could not be found.", apiErr.Message)
+}
+
+func TestRetriesRateLimitedRequests(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Rate limit the first request and ask the client to retry straight away
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Write([]byte(`{"id": 550, "title": "Fight Club"}`))
+	})
+	client.retryBackoff = time.Millisecond
+
+	details, err := client.GetMovieDetails(context.TODO(), "550")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Fight Club", details.Title)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestRateLimitedAfterRetries(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	client.retryBackoff = time.Millisecond
+
+	_, err := client.GetMovieDetails(context.TODO(), "550")
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, int32(DefaultMaxRetries+1), requests.Load())
+}
+
+func TestRetryAfterLongerThanMaxWait(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := client.GetMovieDetails(context.TODO(), "550")
+
+	var apiErr *Error
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, time.Hour, apiErr.RetryAfter)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestUpstreamError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"status_code": 7, "status_message": "Invalid API key: You must be granted a valid key."}`))
+	})
+
+	_, err := client.GetTrendingMovies(context.TODO(), "day")
+
+	assert.ErrorIs(t, err, ErrUpstream)
+}
@@ -0,0 +1,126 @@
+package tmdb
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DepartmentActing is the department cast credits are filed under.
+const DepartmentActing = "Acting"
+
+// PersonCredit is a single movie or TV credit of a person, normalized so cast and crew credits share the same fields.
+type PersonCredit struct {
+	ID          int     `json:"id"`
+	MediaType   string  `json:"media_type"`
+	Title       string  `json:"title"`
+	ReleaseDate string  `json:"release_date"` // First air date for TV shows
+	PosterPath  string  `json:"poster_path"`
+	VoteAverage float64 `json:"vote_average"`
+	Department  string  `json:"department"`
+	Job         string  `json:"job,omitempty"`
+	Character   string  `json:"character,omitempty"`
+	CreditID    string  `json:"credit_id"`
+}
+
+// PersonCreditsResponse holds the combined filmography of a person, newest first.
+type PersonCreditsResponse struct {
+	ID   int            `json:"id"`
+	Cast []PersonCredit `json:"cast"`
+	Crew []PersonCredit `json:"crew"`
+}
+
+// combinedCredit is a credit as TMDB returns it, where movies and TV shows use different title and date fields.
+type combinedCredit struct {
+	ID           int     `json:"id"`
+	MediaType    string  `json:"media_type"`
+	Title        string  `json:"title"`
+	Name         string  `json:"name"`
+	ReleaseDate  string  `json:"release_date"`
+	FirstAirDate string  `json:"first_air_date"`
+	PosterPath   string  `json:"poster_path"`
+	VoteAverage  float64 `json:"vote_average"`
+	Department   string  `json:"department"`
+	Job          string  `json:"job"`
+	Character    string  `json:"character"`
+	CreditID     string  `json:"credit_id"`
+}
+
+type combinedCreditsResponse struct {
+	ID   int              `json:"id"`
+	Cast []combinedCredit `json:"cast"`
+	Crew []combinedCredit `json:"crew"`
+}
+
+// normalize converts the credit to a PersonCredit, filing it under the given department when TMDB leaves it out.
+func (credit combinedCredit) normalize(department string) PersonCredit {
+	normalized := PersonCredit{
+		ID:          credit.ID,
+		MediaType:   credit.MediaType,
+		Title:       credit.Title,
+		ReleaseDate: credit.ReleaseDate,
+		PosterPath:  credit.PosterPath,
+		VoteAverage: credit.VoteAverage,
+		Department:  credit.Department,
+		Job:         credit.Job,
+		Character:   credit.Character,
+		CreditID:    credit.CreditID,
+	}
+
+	if normalized.Title == "" {
+		normalized.Title = credit.Name
+	}
+
+	if normalized.ReleaseDate == "" {
+		normalized.ReleaseDate = credit.FirstAirDate
+	}
+
+	if normalized.Department == "" {
+		normalized.Department = department
+	}
+
+	return normalized
+}
+
+// GetPersonCredits fetches the movie and TV cast and crew credits of a person sorted by release date, newest first.
+// When department is set only credits of that department are returned, e.g. "Acting" only returns the cast credits.
+func (c *Client) GetPersonCredits(ctx context.Context, personID, department string) (*PersonCreditsResponse, error) {
+	var combinedCredits combinedCreditsResponse
+	params := url.Values{"language": {"en-US"}}
+	if err := c.get(ctx, EndpointPersonCredits, "/person/"+url.PathEscape(personID)+"/combined_credits", params, &combinedCredits); err != nil {
+		return nil, err
+	}
+
+	credits := &PersonCreditsResponse{
+		ID:   combinedCredits.ID,
+		Cast: filterCredits(combinedCredits.Cast, DepartmentActing, department),
+		Crew: filterCredits(combinedCredits.Crew, "", department),
+	}
+
+	return credits, nil
+}
+
+// filterCredits normalizes the credits, keeps the ones in the department if it is set and sorts them newest first.
+func filterCredits(credits []combinedCredit, defaultDepartment, department string) []PersonCredit {
+	filtered := []PersonCredit{}
+	for _, credit := range credits {
+		normalized := credit.normalize(defaultDepartment)
+		if department != "" && !strings.EqualFold(normalized.Department, department) {
+			continue
+		}
+
+		filtered = append(filtered, normalized)
+	}
+
+	// Dates are formatted as YYYY-MM-DD so they sort as strings, undated credits are usually announced projects and go first
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if filtered[i].ReleaseDate == "" || filtered[j].ReleaseDate == "" {
+			return filtered[i].ReleaseDate == "" && filtered[j].ReleaseDate != ""
+		}
+
+		return filtered[i].ReleaseDate > filtered[j].ReleaseDate
+	})
+
+	return filtered
+}
@@ -0,0 +1,50 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const combinedCreditsBody = `{"id": 525, "cast": [
+	{"id": 1, "media_type": "movie", "title": "Cameo", "release_date": "2001-05-01", "character": "Himself"}
+], "crew": [
+	{"id": 27205, "media_type": "movie", "title": "Inception", "release_date": "2010-07-15", "department": "Directing", "job": "Director"},
+	{"id": 155, "media_type": "movie", "title": "The Dark Knight", "release_date": "2008-07-16", "department": "Writing", "job": "Screenplay"},
+	{"id": 60, "media_type": "tv", "name": "Untitled Series", "first_air_date": "", "department": "Directing", "job": "Director"},
+	{"id": 157336, "media_type": "movie", "title": "Interstellar", "release_date": "2014-11-05", "department": "Directing", "job": "Director"}
+]}`
+
+func TestGetPersonCreditsSortsByReleaseDate(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/person/525/combined_credits", r.URL.Path)
+
+		w.Write([]byte(combinedCreditsBody))
+	})
+
+	credits, err := client.GetPersonCredits(context.TODO(), "525", "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, DepartmentActing, credits.Cast[0].Department)
+
+	titles := []string{}
+	for _, credit := range credits.Crew {
+		titles = append(titles, credit.Title)
+	}
+	assert.Equal(t, []string{"Untitled Series", "Interstellar", "Inception", "The Dark Knight"}, titles)
+}
+
+func TestGetPersonCreditsFiltersByDepartment(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(combinedCreditsBody))
+	})
+
+	credits, err := client.GetPersonCredits(context.TODO(), "525", "writing")
+
+	assert.Nil(t, err)
+	assert.Empty(t, credits.Cast)
+	assert.Len(t, credits.Crew, 1)
+	assert.Equal(t, "Screenplay", credits.Crew[0].Job)
+}
@@ -0,0 +1,104 @@
+package tmdb
+
+import (
+	"context"
+	"infy/models"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DiscoverSortOrders are the sort orders accepted by DiscoverMovies.
+var DiscoverSortOrders = []string{
+	"popularity.desc", "popularity.asc",
+	"primary_release_date.desc", "primary_release_date.asc",
+	"vote_average.desc", "vote_average.asc",
+	"vote_count.desc", "vote_count.asc",
+	"revenue.desc", "revenue.asc",
+	"title.asc", "title.desc",
+}
+
+type GenresResponse struct {
+	Genres []models.Genre `json:"genres"`
+}
+
+// DiscoverOptions holds the filters of a discover request. Zero values leave the filter out.
+type DiscoverOptions struct {
+	Page             int
+	GenreIDs         []int
+	YearFrom         int
+	YearTo           int
+	MinVoteAverage   float64
+	RuntimeMin       int
+	RuntimeMax       int
+	SortBy           string
+	OriginalLanguage string
+}
+
+// values builds the TMDB discover query parameters for the options.
+func (o DiscoverOptions) values() url.Values {
+	params := url.Values{}
+
+	if o.Page > 0 {
+		params.Set("page", strconv.Itoa(o.Page))
+	}
+
+	if len(o.GenreIDs) > 0 {
+		genres := make([]string, 0, len(o.GenreIDs))
+		for _, id := range o.GenreIDs {
+			genres = append(genres, strconv.Itoa(id))
+		}
+		// A comma means the movie must have all of the genres
+		params.Set("with_genres", strings.Join(genres, ","))
+	}
+
+	if o.YearFrom > 0 {
+		params.Set("primary_release_date.gte", strconv.Itoa(o.YearFrom)+"-01-01")
+	}
+
+	if o.YearTo > 0 {
+		params.Set("primary_release_date.lte", strconv.Itoa(o.YearTo)+"-12-31")
+	}
+
+	if o.MinVoteAverage > 0 {
+		params.Set("vote_average.gte", strconv.FormatFloat(o.MinVoteAverage, 'f', -1, 64))
+	}
+
+	if o.RuntimeMin > 0 {
+		params.Set("with_runtime.gte", strconv.Itoa(o.RuntimeMin))
+	}
+
+	if o.RuntimeMax > 0 {
+		params.Set("with_runtime.lte", strconv.Itoa(o.RuntimeMax))
+	}
+
+	if o.SortBy != "" {
+		params.Set("sort_by", o.SortBy)
+	}
+
+	if o.OriginalLanguage != "" {
+		params.Set("with_original_language", o.OriginalLanguage)
+	}
+
+	return params
+}
+
+// GetMovieGenres fetches the list of official movie genres from TMDB.
+func (c *Client) GetMovieGenres(ctx context.Context) (*GenresResponse, error) {
+	var genresResponse GenresResponse
+	if err := c.get(ctx, EndpointGenres, "/genre/movie/list", url.Values{"language": {"en-US"}}, &genresResponse); err != nil {
+		return nil, err
+	}
+
+	return &genresResponse, nil
+}
+
+// DiscoverMovies finds movies matching the given filters. Results are shaped like search results.
+func (c *Client) DiscoverMovies(ctx context.Context, opts DiscoverOptions) (*MovieSearchResponse, error) {
+	var discoverResponse MovieSearchResponse
+	if err := c.get(ctx, EndpointDiscover, "/discover/movie", opts.values(), &discoverResponse); err != nil {
+		return nil, err
+	}
+
+	return &discoverResponse, nil
+}
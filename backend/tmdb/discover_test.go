@@ -0,0 +1,41 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoverMoviesSendsFilters(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		assert.Equal(t, "/discover/movie", r.URL.Path)
+		assert.Equal(t, "28,12", query.Get("with_genres"))
+		assert.Equal(t, "1990-01-01", query.Get("primary_release_date.gte"))
+		assert.Equal(t, "1999-12-31", query.Get("primary_release_date.lte"))
+		assert.Equal(t, "7.5", query.Get("vote_average.gte"))
+		assert.Equal(t, "90", query.Get("with_runtime.gte"))
+		assert.Equal(t, "vote_average.desc", query.Get("sort_by"))
+		assert.Equal(t, "ja", query.Get("with_original_language"))
+		assert.False(t, query.Has("with_runtime.lte"))
+
+		w.Write([]byte(`{"page": 1, "total_pages": 4, "total_results": 80, "results": [{"id": 129, "title": "Spirited Away"}]}`))
+	})
+
+	results, err := client.DiscoverMovies(context.TODO(), DiscoverOptions{
+		GenreIDs:         []int{28, 12},
+		YearFrom:         1990,
+		YearTo:           1999,
+		MinVoteAverage:   7.5,
+		RuntimeMin:       90,
+		SortBy:           "vote_average.desc",
+		OriginalLanguage: "ja",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 80, results.TotalResults)
+	assert.Equal(t, "Spirited Away", results.Results[0].Title)
+}
@@ -0,0 +1,89 @@
+package tmdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Errors returned by the client, wrapped in an *Error carrying the details of the response.
+var (
+	ErrNotFound    = errors.New("tmdb: resource not found")
+	ErrRateLimited = errors.New("tmdb: rate limit exceeded")
+	ErrUpstream    = errors.New("tmdb: upstream error")
+)
+
+// Error describes a failed TMDB request. Use errors.Is with ErrNotFound, ErrRateLimited or ErrUpstream to check its kind.
+type Error struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is how long TMDB asked us to wait before retrying, if it said so.
+	RetryAfter time.Duration
+	kind       error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%v: %s", e.kind, e.Message)
+	}
+
+	return fmt.Sprintf("%v: status %d: %s", e.kind, e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// errorResponse is the body TMDB sends along with a failed request.
+type errorResponse struct {
+	StatusCode    int    `json:"status_code"`
+	StatusMessage string `json:"status_message"`
+}
+
+// newResponseError builds the *Error matching a non-200 TMDB response.
+func newResponseError(resp *response) *Error {
+	err := &Error{StatusCode: resp.status, Message: http.StatusText(resp.status), RetryAfter: resp.retryAfter}
+
+	var body errorResponse
+	if json.Unmarshal(resp.body, &body) == nil && body.StatusMessage != "" {
+		err.Message = body.StatusMessage
+	}
+
+	switch {
+	case resp.status == http.StatusNotFound:
+		err.kind = ErrNotFound
+	case resp.status == http.StatusTooManyRequests:
+		err.kind = ErrRateLimited
+	default:
+		err.kind = ErrUpstream
+	}
+
+	return err
+}
+
+// newTransportError wraps an error from sending the request, such as a timeout, as an upstream error.
+func newTransportError(err error) *Error {
+	return &Error{Message: err.Error(), kind: ErrUpstream}
+}
+
+// parseRetryAfter reads the Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
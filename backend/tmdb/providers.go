@@ -0,0 +1,85 @@
+package tmdb
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultRegion is the watch provider region used when none is given.
+const DefaultRegion = "US"
+
+type Provider struct {
+	ID              int    `json:"provider_id"`
+	Name            string `json:"provider_name"`
+	LogoPath        string `json:"logo_path"`
+	DisplayPriority int    `json:"display_priority"`
+}
+
+// RegionProviders lists where a movie can be watched in a single region, grouped by how it is offered.
+type RegionProviders struct {
+	Link     string     `json:"link"`
+	Flatrate []Provider `json:"flatrate"` // Included in a subscription
+	Free     []Provider `json:"free"`
+	Ads      []Provider `json:"ads"`
+	Rent     []Provider `json:"rent"`
+	Buy      []Provider `json:"buy"`
+}
+
+// StreamingOn returns the providers the movie can be streamed on without renting or buying it,
+// limited to the given provider IDs.
+func (p *RegionProviders) StreamingOn(serviceIDs []int) []Provider {
+	providers := []Provider{}
+	for _, group := range [][]Provider{p.Flatrate, p.Free, p.Ads} {
+		for _, provider := range group {
+			if slices.Contains(serviceIDs, provider.ID) {
+				providers = append(providers, provider)
+			}
+		}
+	}
+
+	return providers
+}
+
+type WatchProvidersResponse struct {
+	ID      int                        `json:"id"`
+	Results map[string]RegionProviders `json:"results"` // Keyed by ISO 3166-1 country code
+}
+
+type ProvidersResponse struct {
+	Results []Provider `json:"results"`
+}
+
+// GetMovieWatchProviders fetches where a movie can be streamed, rented or bought in the given region.
+// Regions without any providers return an empty result rather than an error.
+func (c *Client) GetMovieWatchProviders(ctx context.Context, movieID, region string) (*RegionProviders, error) {
+	var providersResponse WatchProvidersResponse
+	// TMDB always returns every region, so the whole response is cached once per movie
+	if err := c.get(ctx, EndpointProviders, "/movie/"+url.PathEscape(movieID)+"/watch/providers", nil, &providersResponse); err != nil {
+		return nil, err
+	}
+
+	providers := providersResponse.Results[regionCode(region)]
+	return &providers, nil
+}
+
+// GetMovieProviders fetches the streaming services that offer movies in the given region.
+func (c *Client) GetMovieProviders(ctx context.Context, region string) (*ProvidersResponse, error) {
+	var providersResponse ProvidersResponse
+	params := url.Values{"watch_region": {regionCode(region)}}
+	if err := c.get(ctx, EndpointProviders, "/watch/providers/movie", params, &providersResponse); err != nil {
+		return nil, err
+	}
+
+	return &providersResponse, nil
+}
+
+// regionCode normalizes a region to the upper case country code TMDB uses, falling back to DefaultRegion.
+func regionCode(region string) string {
+	if region == "" {
+		return DefaultRegion
+	}
+
+	return strings.ToUpper(region)
+}
@@ -0,0 +1,46 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMovieWatchProviders(t *testing.T) {
+	requests := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/movie/550/watch/providers", r.URL.Path)
+
+		w.Write([]byte(`{"id": 550, "results": {
+			"US": {"flatrate": [{"provider_id": 8, "provider_name": "Netflix"}], "rent": [{"provider_id": 2, "provider_name": "Apple TV"}]},
+			"DE": {"flatrate": [{"provider_id": 9, "provider_name": "Prime Video"}]}
+		}}`))
+	})
+
+	providers, err := client.GetMovieWatchProviders(context.TODO(), "550", "de")
+	assert.Nil(t, err)
+	assert.Equal(t, "Prime Video", providers.Flatrate[0].Name)
+
+	// Every region is served from the same cached response
+	providers, err = client.GetMovieWatchProviders(context.TODO(), "550", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "Netflix", providers.Flatrate[0].Name)
+	assert.Equal(t, 1, requests)
+
+	providers, err = client.GetMovieWatchProviders(context.TODO(), "550", "FR")
+	assert.Nil(t, err)
+	assert.Empty(t, providers.Flatrate)
+}
+
+func TestStreamingOnOnlyMatchesServices(t *testing.T) {
+	providers := RegionProviders{
+		Flatrate: []Provider{{ID: 8, Name: "Netflix"}, {ID: 337, Name: "Disney Plus"}},
+		Rent:     []Provider{{ID: 2, Name: "Apple TV"}},
+	}
+
+	assert.Equal(t, []Provider{{ID: 8, Name: "Netflix"}}, providers.StreamingOn([]int{8, 2}))
+	assert.Empty(t, providers.StreamingOn([]int{2}))
+}
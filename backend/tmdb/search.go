@@ -0,0 +1,54 @@
+package tmdb
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// MaxPage is the highest page TMDB returns for paginated lists.
+const MaxPage = 500
+
+// Pagination holds the paging fields TMDB sends with every paginated list.
+type Pagination struct {
+	Page         int `json:"page"`
+	TotalPages   int `json:"total_pages"`
+	TotalResults int `json:"total_results"`
+}
+
+// SearchOptions holds the optional filters of a movie or people search.
+type SearchOptions struct {
+	Page         int
+	Year         int // Only used by movie searches
+	IncludeAdult bool
+	Language     string
+	Region       string // Only used by movie searches
+}
+
+// values builds the query parameters for the search, leaving out unset options so TMDB uses its defaults.
+func (o SearchOptions) values(query string, movies bool) url.Values {
+	params := url.Values{"query": {query}}
+
+	if o.Page > 0 {
+		params.Set("page", strconv.Itoa(o.Page))
+	}
+
+	if o.IncludeAdult {
+		params.Set("include_adult", "true")
+	}
+
+	if o.Language != "" {
+		params.Set("language", o.Language)
+	}
+
+	if movies {
+		if o.Year > 0 {
+			params.Set("year", strconv.Itoa(o.Year))
+		}
+
+		if o.Region != "" {
+			params.Set("region", o.Region)
+		}
+	}
+
+	return params
+}
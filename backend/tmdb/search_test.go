@@ -0,0 +1,49 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchMoviesEscapesQueryAndPaginates(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		// Assert special characters reach TMDB as part of the query instead of breaking the URL
+		assert.Equal(t, "/search/movie", r.URL.Path)
+		assert.Equal(t, "Fast & Furious #7", query.Get("query"))
+		assert.Equal(t, "2", query.Get("page"))
+		assert.Equal(t, "2015", query.Get("year"))
+		assert.Equal(t, "US", query.Get("region"))
+		assert.Equal(t, "test-key", query.Get("api_key"))
+
+		w.Write([]byte(`{"page": 2, "total_pages": 3, "total_results": 45, "results": [{"id": 168259, "title": "Furious 7"}]}`))
+	})
+
+	results, err := client.SearchMovies(context.TODO(), "Fast & Furious #7", SearchOptions{Page: 2, Year: 2015, Region: "US"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, Pagination{Page: 2, TotalPages: 3, TotalResults: 45}, results.Pagination)
+	assert.Equal(t, "Furious 7", results.Results[0].Title)
+}
+
+func TestSearchActorsIgnoresMovieOnlyOptions(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		assert.Equal(t, "/search/person", r.URL.Path)
+		assert.Equal(t, "true", query.Get("include_adult"))
+		assert.Equal(t, "en-US", query.Get("language"))
+		assert.False(t, query.Has("year"))
+		assert.False(t, query.Has("region"))
+
+		w.Write([]byte(`{"page": 1, "total_pages": 1, "total_results": 0, "results": []}`))
+	})
+
+	_, err := client.SearchActors(context.TODO(), "Keanu", SearchOptions{Year: 1999, Region: "US", IncludeAdult: true, Language: "en-US"})
+
+	assert.Nil(t, err)
+}
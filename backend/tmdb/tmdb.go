@@ -0,0 +1,286 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"infy/models"
+	"net/url"
+)
+
+// Struct definitions for parsing JSON responses from TMDB API.
+type MovieSearchResponse struct {
+	Pagination
+	Results []struct {
+		ID         int     `json:"id"`
+		Title      string  `json:"title"`
+		PosterPath string  `json:"poster_path"`
+		Year       string  `json:"release_date"`
+		Rating     float64 `json:"vote_average"`
+		Plot       string  `json:"overview"`
+	} `json:"results"`
+}
+
+type PersonSearchResponse struct {
+	Pagination
+	Results []struct {
+		ID                 int     `json:"id"`
+		Name               string  `json:"name"`
+		KnownForDepartment string  `json:"known_for_department"` // Acting, Directing, Writing, etc.
+		ProfilePath        string  `json:"profile_path"`
+		Popularity         float64 `json:"popularity"`
+		KnownFor           []struct {
+			ID         int    `json:"id"`
+			MediaType  string `json:"media_type"`
+			Title      string `json:"title"` // Set for movies
+			Name       string `json:"name"`  // Set for TV shows
+			PosterPath string `json:"poster_path"`
+		} `json:"known_for"`
+	} `json:"results"`
+}
+
+type TrendingResponse struct {
+	Results []struct {
+		ID         int     `json:"id"`
+		Title      string  `json:"title"`
+		PosterPath string  `json:"poster_path"`
+		Year       string  `json:"release_date"`
+		Rating     float64 `json:"vote_average"`
+		Plot       string  `json:"overview"`
+	} `json:"results"`
+}
+
+type CastResponse struct {
+	Cast []struct {
+		ID          int    `json:"id"`
+		CastID      int    `json:"cast_id"`
+		Character   string `json:"character"`
+		Name        string `json:"name"`
+		ProfilePath string `json:"profile_path"`
+	} `json:"cast"`
+	Crew []struct {
+		ID          int    `json:"id"`
+		CreditID    string `json:"credit_id"`
+		Name        string `json:"name"`
+		Department  string `json:"department"`
+		Job         string `json:"job"` // Director, Screenplay, Original Music Composer, etc.
+		ProfilePath string `json:"profile_path"`
+	} `json:"crew"`
+}
+
+type ReviewResponse struct {
+	Results []struct {
+		Author  string `json:"author"`
+		Content string `json:"content"`
+		ID      string `json:"id"`
+		URL     string `json:"url"`
+	} `json:"results"`
+}
+
+type SimilarMoviesResponse struct {
+	Results []struct {
+		ID         int    `json:"id"`
+		Title      string `json:"title"`
+		PosterPath string `json:"poster_path"`
+		Overview   string `json:"overview"`
+	} `json:"results"`
+}
+
+type MovieDetails struct {
+	models.Movie
+	Overview     string         `json:"overview"`
+	BackdropPath string         `json:"backdrop_path"`
+	Runtime      int            `json:"runtime"`
+	ReleaseDate  string         `json:"release_date"`
+	Genres       []models.Genre `json:"genres"`
+	VoteAverage  float64        `json:"vote_average"`
+}
+
+// for actor page possibly
+type ActorDetailsResponse struct {
+	Biography    string `json:"biography"`
+	Birthday     string `json:"birthday"`
+	Deathday     string `json:"deathday"`
+	Gender       int    `json:"gender"`
+	Name         string `json:"name"`
+	PlaceOfBirth string `json:"place_of_birth"`
+	ProfilePath  string `json:"profile_path"`
+}
+
+// For actor movies
+type ActorMovieCreditsResponse struct {
+	Cast []struct {
+		Adult            bool    `json:"adult"`
+		BackdropPath     string  `json:"backdrop_path"`
+		GenreIDs         []int   `json:"genre_ids"`
+		ID               int     `json:"id"`
+		OriginalLanguage string  `json:"original_language"`
+		OriginalTitle    string  `json:"original_title"`
+		Overview         string  `json:"overview"`
+		PosterPath       string  `json:"poster_path"`
+		ReleaseDate      string  `json:"release_date"`
+		Title            string  `json:"title"`
+		Video            bool    `json:"video"`
+		VoteAverage      float64 `json:"vote_average"`
+		VoteCount        int     `json:"vote_count"`
+		Popularity       float64 `json:"popularity"`
+		Character        string  `json:"character"`
+		CreditID         string  `json:"credit_id"`
+	} `json:"cast"`
+	Crew []struct {
+		ID          int     `json:"id"`
+		Title       string  `json:"title"`
+		PosterPath  string  `json:"poster_path"`
+		ReleaseDate string  `json:"release_date"`
+		VoteAverage float64 `json:"vote_average"`
+		Department  string  `json:"department"`
+		Job         string  `json:"job"`
+		CreditID    string  `json:"credit_id"`
+	} `json:"crew"`
+	ID int `json:"id"`
+}
+
+type VideoResponse struct {
+	Results []struct {
+		ID   string `json:"id"`
+		Key  string `json:"key"`
+		Name string `json:"name"`
+		Site string `json:"site"` // YouTube or Vimeo
+		Type string `json:"type"` // Trailer, Teaser, etc.
+	} `json:"results"`
+}
+
+// SearchMovies searches TMDB for movies matching the given title.
+func (c *Client) SearchMovies(ctx context.Context, query string, opts SearchOptions) (*MovieSearchResponse, error) {
+	var searchResponse MovieSearchResponse
+	if err := c.get(ctx, EndpointSearch, "/search/movie", opts.values(query, true), &searchResponse); err != nil {
+		return nil, err
+	}
+
+	return &searchResponse, nil
+}
+
+// SearchActors searches TMDB for people matching the given name.
+func (c *Client) SearchActors(ctx context.Context, query string, opts SearchOptions) (*PersonSearchResponse, error) {
+	var searchResponse PersonSearchResponse
+	if err := c.get(ctx, EndpointSearch, "/search/person", opts.values(query, false), &searchResponse); err != nil {
+		return nil, err
+	}
+
+	return &searchResponse, nil
+}
+
+// GetMovieDetails fetches detailed information about a specific movie from TMDB.
+// Movies are also saved to the catalog when fetched from TMDB or missing from it.
+func (c *Client) GetMovieDetails(ctx context.Context, movieID string) (*MovieDetails, error) {
+	movieDetails, err := c.fetchMovieWithCredits(ctx, movieID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &movieDetails.MovieDetails, nil
+}
+
+// GetMovie fetches the limited movie snapshot that is embedded in posts.
+func (c *Client) GetMovie(ctx context.Context, movieID string) (*models.Movie, error) {
+	movieDetails, err := c.GetMovieDetails(ctx, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	movie := models.Movie{
+		ID:         movieDetails.ID,
+		Title:      movieDetails.Title,
+		PosterPath: movieDetails.PosterPath,
+		Tagline:    movieDetails.Tagline,
+		MediaType:  models.MediaTypeMovie,
+	}
+
+	return &movie, nil
+}
+
+// IsValidMovieID checks if a given movie ID is valid by making an API call to TMDB.
+func (c *Client) IsValidMovieID(ctx context.Context, movieID string) (bool, error) {
+	// Shares the cache entry with GetMovieDetails so validating a movie before using it costs one request,
+	// and saves the movie to the catalog so watch lists can be joined against it
+	_, err := c.fetchMovieWithCredits(ctx, movieID, false)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetTrendingMovies fetches trending movies from TMDB based on the specified time window (day or week).
+func (c *Client) GetTrendingMovies(ctx context.Context, timeWindow string) (*TrendingResponse, error) {
+	var trendingResponse TrendingResponse
+	if err := c.get(ctx, EndpointTrending, "/trending/movie/"+url.PathEscape(timeWindow), nil, &trendingResponse); err != nil {
+		return nil, err
+	}
+
+	return &trendingResponse, nil
+}
+
+// GetMovieCast fetches the cast list for a specific movie from TMDB.
+func (c *Client) GetMovieCast(ctx context.Context, movieID string) (*CastResponse, error) {
+	var castResponse CastResponse
+	if err := c.get(ctx, EndpointCredits, "/movie/"+url.PathEscape(movieID)+"/credits", nil, &castResponse); err != nil {
+		return nil, err
+	}
+
+	return &castResponse, nil
+}
+
+// GetMovieReviews fetches reviews for a specific movie from TMDB.
+func (c *Client) GetMovieReviews(ctx context.Context, movieID string) (*ReviewResponse, error) {
+	var reviewsResponse ReviewResponse
+	if err := c.get(ctx, EndpointReviews, "/movie/"+url.PathEscape(movieID)+"/reviews", nil, &reviewsResponse); err != nil {
+		return nil, err
+	}
+
+	return &reviewsResponse, nil
+}
+
+// GetSimilarMovies fetches a list of movies similar to a specified movie from TMDB.
+func (c *Client) GetSimilarMovies(ctx context.Context, movieID string) (*SimilarMoviesResponse, error) {
+	var similarMoviesResponse SimilarMoviesResponse
+	if err := c.get(ctx, EndpointSimilar, "/movie/"+url.PathEscape(movieID)+"/similar", nil, &similarMoviesResponse); err != nil {
+		return nil, err
+	}
+
+	return &similarMoviesResponse, nil
+}
+
+// GetActorDetails fetches the details of a person for the actor page.
+func (c *Client) GetActorDetails(ctx context.Context, actorID string) (*ActorDetailsResponse, error) {
+	var actorDetails ActorDetailsResponse
+	if err := c.get(ctx, EndpointPerson, "/person/"+url.PathEscape(actorID), nil, &actorDetails); err != nil {
+		return nil, err
+	}
+
+	return &actorDetails, nil
+}
+
+// GetActorMovieCredits fetches the movies a person has appeared in for the actor page.
+func (c *Client) GetActorMovieCredits(ctx context.Context, actorID string) (*ActorMovieCreditsResponse, error) {
+	var movieCredits ActorMovieCreditsResponse
+	params := url.Values{"language": {"en-US"}}
+	if err := c.get(ctx, EndpointPersonMovie, "/person/"+url.PathEscape(actorID)+"/movie_credits", params, &movieCredits); err != nil {
+		return nil, err
+	}
+
+	return &movieCredits, nil
+}
+
+// GetMovieTrailers fetches the videos (trailers, teasers, etc.) for a specific movie from TMDB.
+func (c *Client) GetMovieTrailers(ctx context.Context, movieID string) (*VideoResponse, error) {
+	var videoResponse VideoResponse
+	params := url.Values{"language": {"en-US"}}
+	if err := c.get(ctx, EndpointVideos, "/movie/"+url.PathEscape(movieID)+"/videos", params, &videoResponse); err != nil {
+		return nil, err
+	}
+
+	return &videoResponse, nil
+}
@@ -0,0 +1,188 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"infy/models"
+	"net/url"
+	"strconv"
+)
+
+type TVDetails struct {
+	ID               int            `json:"id"`
+	Name             string         `json:"name"`
+	Tagline          string         `json:"tagline"`
+	Overview         string         `json:"overview"`
+	PosterPath       string         `json:"poster_path"`
+	BackdropPath     string         `json:"backdrop_path"`
+	FirstAirDate     string         `json:"first_air_date"`
+	LastAirDate      string         `json:"last_air_date"`
+	Status           string         `json:"status"`
+	NumberOfSeasons  int            `json:"number_of_seasons"`
+	NumberOfEpisodes int            `json:"number_of_episodes"`
+	EpisodeRunTime   []int          `json:"episode_run_time"`
+	Genres           []models.Genre `json:"genres"`
+	VoteAverage      float64        `json:"vote_average"`
+	Seasons          []struct {
+		ID           int    `json:"id"`
+		Name         string `json:"name"`
+		SeasonNumber int    `json:"season_number"`
+		EpisodeCount int    `json:"episode_count"`
+		AirDate      string `json:"air_date"`
+		PosterPath   string `json:"poster_path"`
+		Overview     string `json:"overview"`
+	} `json:"seasons"`
+}
+
+type TVEpisode struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Overview      string  `json:"overview"`
+	SeasonNumber  int     `json:"season_number"`
+	EpisodeNumber int     `json:"episode_number"`
+	AirDate       string  `json:"air_date"`
+	Runtime       int     `json:"runtime"`
+	StillPath     string  `json:"still_path"`
+	VoteAverage   float64 `json:"vote_average"`
+}
+
+type TVSeasonResponse struct {
+	ID           int         `json:"id"`
+	Name         string      `json:"name"`
+	Overview     string      `json:"overview"`
+	AirDate      string      `json:"air_date"`
+	SeasonNumber int         `json:"season_number"`
+	PosterPath   string      `json:"poster_path"`
+	Episodes     []TVEpisode `json:"episodes"`
+}
+
+type SimilarTVResponse struct {
+	Results []struct {
+		ID         int    `json:"id"`
+		Name       string `json:"name"`
+		PosterPath string `json:"poster_path"`
+		Overview   string `json:"overview"`
+	} `json:"results"`
+}
+
+// tvPath builds the path of a TV show resource from the show ID and optional sub paths.
+func tvPath(tvID string, parts ...string) string {
+	path := "/tv/" + url.PathEscape(tvID)
+	for _, part := range parts {
+		path += "/" + url.PathEscape(part)
+	}
+
+	return path
+}
+
+// GetTVDetails fetches detailed information about a specific TV show from TMDB.
+func (c *Client) GetTVDetails(ctx context.Context, tvID string) (*TVDetails, error) {
+	var tvDetails TVDetails
+	if err := c.get(ctx, EndpointTV, tvPath(tvID), nil, &tvDetails); err != nil {
+		return nil, err
+	}
+
+	return &tvDetails, nil
+}
+
+// GetTVShow fetches the limited snapshot of a TV show that is embedded in posts.
+func (c *Client) GetTVShow(ctx context.Context, tvID string) (*models.Movie, error) {
+	tvDetails, err := c.GetTVDetails(ctx, tvID)
+	if err != nil {
+		return nil, err
+	}
+
+	show := models.Movie{
+		ID:         tvDetails.ID,
+		Title:      tvDetails.Name,
+		PosterPath: tvDetails.PosterPath,
+		Tagline:    tvDetails.Tagline,
+		MediaType:  models.MediaTypeTV,
+	}
+
+	return &show, nil
+}
+
+// GetMedia fetches the post snapshot of a movie or TV show depending on the media type.
+func (c *Client) GetMedia(ctx context.Context, mediaType, mediaID string) (*models.Movie, error) {
+	if mediaType == models.MediaTypeTV {
+		return c.GetTVShow(ctx, mediaID)
+	}
+
+	return c.GetMovie(ctx, mediaID)
+}
+
+// IsValidTVID checks if a given TV show ID is valid by making an API call to TMDB.
+func (c *Client) IsValidTVID(ctx context.Context, tvID string) (bool, error) {
+	_, err := c.fetch(ctx, EndpointTV, tvPath(tvID), nil)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// IsValidMediaID checks if a given movie or TV show ID is valid depending on the media type.
+func (c *Client) IsValidMediaID(ctx context.Context, mediaType, mediaID string) (bool, error) {
+	if mediaType == models.MediaTypeTV {
+		return c.IsValidTVID(ctx, mediaID)
+	}
+
+	return c.IsValidMovieID(ctx, mediaID)
+}
+
+// GetTVCast fetches the cast list for a specific TV show from TMDB.
+func (c *Client) GetTVCast(ctx context.Context, tvID string) (*CastResponse, error) {
+	var castResponse CastResponse
+	if err := c.get(ctx, EndpointCredits, tvPath(tvID, "credits"), nil, &castResponse); err != nil {
+		return nil, err
+	}
+
+	return &castResponse, nil
+}
+
+// GetSimilarTV fetches a list of TV shows similar to a specified show from TMDB.
+func (c *Client) GetSimilarTV(ctx context.Context, tvID string) (*SimilarTVResponse, error) {
+	var similarResponse SimilarTVResponse
+	if err := c.get(ctx, EndpointSimilar, tvPath(tvID, "similar"), nil, &similarResponse); err != nil {
+		return nil, err
+	}
+
+	return &similarResponse, nil
+}
+
+// GetTVTrailers fetches the videos (trailers, teasers, etc.) for a specific TV show from TMDB.
+func (c *Client) GetTVTrailers(ctx context.Context, tvID string) (*VideoResponse, error) {
+	var videoResponse VideoResponse
+	params := url.Values{"language": {"en-US"}}
+	if err := c.get(ctx, EndpointVideos, tvPath(tvID, "videos"), params, &videoResponse); err != nil {
+		return nil, err
+	}
+
+	return &videoResponse, nil
+}
+
+// GetTVSeason fetches a season of a TV show with its episodes from TMDB.
+func (c *Client) GetTVSeason(ctx context.Context, tvID string, seasonNumber int) (*TVSeasonResponse, error) {
+	var seasonResponse TVSeasonResponse
+	path := tvPath(tvID, "season", strconv.Itoa(seasonNumber))
+	if err := c.get(ctx, EndpointTVSeason, path, nil, &seasonResponse); err != nil {
+		return nil, err
+	}
+
+	return &seasonResponse, nil
+}
+
+// GetTVEpisode fetches a single episode of a TV show from TMDB.
+func (c *Client) GetTVEpisode(ctx context.Context, tvID string, seasonNumber, episodeNumber int) (*TVEpisode, error) {
+	var episode TVEpisode
+	path := tvPath(tvID, "season", strconv.Itoa(seasonNumber), "episode", strconv.Itoa(episodeNumber))
+	if err := c.get(ctx, EndpointTVSeason, path, nil, &episode); err != nil {
+		return nil, err
+	}
+
+	return &episode, nil
+}
@@ -0,0 +1,49 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"infy/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMediaFetchesTVShows(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tv/1396", r.URL.Path)
+
+		w.Write([]byte(`{"id": 1396, "name": "Breaking Bad", "poster_path": "/bb.jpg"}`))
+	})
+
+	show, err := client.GetMedia(context.TODO(), models.MediaTypeTV, "1396")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Breaking Bad", show.Title)
+	assert.Equal(t, models.MediaTypeTV, show.MediaType)
+}
+
+func TestGetTVEpisode(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tv/1396/season/2/episode/3", r.URL.Path)
+
+		w.Write([]byte(`{"id": 62094, "name": "Bit by a Dead Bee", "season_number": 2, "episode_number": 3}`))
+	})
+
+	episode, err := client.GetTVEpisode(context.TODO(), "1396", 2, 3)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Bit by a Dead Bee", episode.Name)
+}
+
+func TestIsValidTVIDNotFound(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	isValid, err := client.IsValidMediaID(context.TODO(), models.MediaTypeTV, "0")
+
+	assert.Nil(t, err)
+	assert.False(t, isValid)
+}
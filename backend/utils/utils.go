@@ -1,6 +1,11 @@
 package utils
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
 
 // GetEnv is a function that returns the value of an environment variable
 func GetEnv(key, defaultVal string) string {
@@ -18,3 +23,35 @@ func IsProd() bool {
 func IsDev() bool {
 	return GetEnv("ENV", "") == "dev"
 }
+
+// GetEnvDuration returns an environment variable parsed as a duration, falling back to the default if it is unset or invalid
+func GetEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	value := GetEnv(key, "")
+	if value == "" {
+		return defaultVal
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %v", key, err)
+		return defaultVal
+	}
+
+	return duration
+}
+
+// GetEnvInt returns an environment variable parsed as an integer, falling back to the default if it is unset or invalid
+func GetEnvInt(key string, defaultVal int) int {
+	value := GetEnv(key, "")
+	if value == "" {
+		return defaultVal
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid number for %s: %v", key, err)
+		return defaultVal
+	}
+
+	return number
+}
@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"bufio"
+	"fmt"
+	"infy/utils"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// DefaultMinPasswordLength is the minimum password length when none is configured
+const DefaultMinPasswordLength = 8
+
+// maxPasswordLength is the most bcrypt can hash, longer passwords would be truncated silently
+const maxPasswordLength = 72
+
+// PasswordPolicy describes the passwords users may choose.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireMixed  bool // Requires both upper and lower case letters
+	RequireDigit  bool
+	RequireSymbol bool
+	breached      map[string]struct{}
+}
+
+// PasswordPolicyFromEnv builds the password policy from the environment, loading the breached
+// passwords from the file at BREACHED_PASSWORDS_FILE if it is set.
+func PasswordPolicyFromEnv() (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:     utils.GetEnvInt("PASSWORD_MIN_LENGTH", DefaultMinPasswordLength),
+		RequireMixed:  utils.GetEnv("PASSWORD_REQUIRE_MIXED_CASE", "false") == "true",
+		RequireDigit:  utils.GetEnv("PASSWORD_REQUIRE_DIGIT", "false") == "true",
+		RequireSymbol: utils.GetEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+	}
+
+	if path := utils.GetEnv("BREACHED_PASSWORDS_FILE", ""); path != "" {
+		if err := policy.LoadBreachedPasswords(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// LoadBreachedPasswords reads a file with one known breached password per line. Passwords are
+// compared case-insensitively so simple capitalization does not get around the list.
+func (p *PasswordPolicy) LoadBreachedPasswords(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			breached[strings.ToLower(password)] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	p.breached = breached
+	return nil
+}
+
+// Check returns why a password is not allowed, or an empty string if it is. The username and email
+// are passed so the password cannot simply repeat them.
+func (p *PasswordPolicy) Check(password, username, email string) string {
+	if len(password) < p.MinLength {
+		return fmt.Sprintf("Password must be at least %d characters", p.MinLength)
+	}
+
+	if len(password) > maxPasswordLength {
+		return fmt.Sprintf("Password must be at most %d bytes", maxPasswordLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	if p.RequireMixed && !(upper && lower) {
+		return "Password must contain both upper and lower case letters"
+	}
+	if p.RequireDigit && !digit {
+		return "Password must contain a digit"
+	}
+	if p.RequireSymbol && !symbol {
+		return "Password must contain a symbol"
+	}
+
+	lowered := strings.ToLower(password)
+	localPart, _, _ := strings.Cut(strings.ToLower(email), "@")
+	if (username != "" && lowered == strings.ToLower(username)) || (localPart != "" && lowered == localPart) {
+		return "Password must not be the same as your username or email"
+	}
+
+	if _, ok := p.breached[lowered]; ok {
+		return "This password has appeared in a data breach, please choose another"
+	}
+
+	return ""
+}
@@ -0,0 +1,127 @@
+// Package validation checks user supplied account data and collects the problems per field.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+
+	MaxNameLength = 50
+	MaxGenres     = 20
+
+	// DefaultMinAge is the minimum age to sign up when none is configured
+	DefaultMinAge = 13
+)
+
+// usernamePattern allows letters, digits, underscores and dots, starting with a letter or digit
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.]*$`)
+
+// FieldErrors maps the JSON name of each invalid field to the reason it was rejected.
+type FieldErrors map[string]string
+
+// Add records the problem of a field, keeping the first one if the field already has one.
+func (e FieldErrors) Add(field, message string) {
+	if message == "" {
+		return
+	}
+
+	if _, ok := e[field]; !ok {
+		e[field] = message
+	}
+}
+
+// Required records that a field is missing when its value is blank.
+func (e FieldErrors) Required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		e.Add(field, "This field is required")
+	}
+}
+
+// Empty reports whether no problems were found.
+func (e FieldErrors) Empty() bool {
+	return len(e) == 0
+}
+
+// Username returns why a username is not allowed, or an empty string if it is valid.
+func Username(username string) string {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return fmt.Sprintf("Username must be between %d and %d characters", MinUsernameLength, MaxUsernameLength)
+	}
+
+	if !usernamePattern.MatchString(username) {
+		return "Username may only contain letters, digits, underscores and dots and must start with a letter or digit"
+	}
+
+	if strings.Contains(username, "..") {
+		return "Username may not contain consecutive dots"
+	}
+
+	return ""
+}
+
+// Name returns why a first or last name is not allowed, or an empty string if it is valid.
+func Name(name string) string {
+	if len([]rune(name)) > MaxNameLength {
+		return fmt.Sprintf("Name must be at most %d characters", MaxNameLength)
+	}
+
+	return ""
+}
+
+// Genres returns why a list of favorite genres is not allowed, or an empty string if it is valid.
+func Genres(genres []string) string {
+	if len(genres) > MaxGenres {
+		return fmt.Sprintf("Pick at most %d genres", MaxGenres)
+	}
+
+	for _, genre := range genres {
+		if strings.TrimSpace(genre) == "" {
+			return "Genres cannot be blank"
+		}
+	}
+
+	return ""
+}
+
+// Email returns why an email address is not valid, or an empty string if it is.
+func Email(email string) string {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "Email address is not valid"
+	}
+
+	return ""
+}
+
+// Age returns how many full years old someone born on dateOfBirth is at the given time.
+func Age(dateOfBirth, now time.Time) int {
+	years := now.Year() - dateOfBirth.Year()
+
+	// Not a year older until the birthday this year has passed
+	if now.Month() < dateOfBirth.Month() || (now.Month() == dateOfBirth.Month() && now.Day() < dateOfBirth.Day()) {
+		years--
+	}
+
+	return years
+}
+
+// DateOfBirth returns why a date of birth is not accepted for someone who must be at least minAge years old,
+// or an empty string if it is.
+func DateOfBirth(dateOfBirth, now time.Time, minAge int) string {
+	if dateOfBirth.After(now) {
+		return "Date of birth cannot be in the future"
+	}
+
+	if Age(dateOfBirth, now) < minAge {
+		return fmt.Sprintf("You must be at least %d years old to sign up", minAge)
+	}
+
+	return ""
+}
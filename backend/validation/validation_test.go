@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsername(t *testing.T) {
+	assert.Empty(t, Username("movie_fan.42"))
+	assert.NotEmpty(t, Username("ab"))
+	assert.NotEmpty(t, Username("this_username_is_way_too_long_to_use"))
+	assert.NotEmpty(t, Username("_hidden"))
+	assert.NotEmpty(t, Username("space name"))
+	assert.NotEmpty(t, Username("dots..dots"))
+}
+
+func TestEmail(t *testing.T) {
+	assert.Empty(t, Email("user@example.com"))
+	assert.NotEmpty(t, Email("user"))
+	assert.NotEmpty(t, Email("User <user@example.com>"))
+}
+
+func TestDateOfBirth(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	// Old enough from the birthday on, not the day before
+	assert.Empty(t, DateOfBirth(time.Date(2011, time.June, 15, 0, 0, 0, 0, time.UTC), now, 13))
+	assert.NotEmpty(t, DateOfBirth(time.Date(2011, time.June, 16, 0, 0, 0, 0, time.UTC), now, 13))
+	assert.NotEmpty(t, DateOfBirth(now.AddDate(0, 0, 1), now, 0))
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := &PasswordPolicy{MinLength: 8, RequireMixed: true, RequireDigit: true}
+
+	assert.Empty(t, policy.Check("Popcorn42", "moviefan", "fan@example.com"))
+	assert.NotEmpty(t, policy.Check("Pop42", "moviefan", "fan@example.com"))
+	assert.NotEmpty(t, policy.Check("popcorn42", "moviefan", "fan@example.com"))
+	assert.NotEmpty(t, policy.Check("Popcornnn", "moviefan", "fan@example.com"))
+	assert.NotEmpty(t, policy.Check("MovieFan1", "moviefan1", "fan@example.com"))
+
+	policy.RequireSymbol = true
+	assert.NotEmpty(t, policy.Check("Popcorn42", "moviefan", "fan@example.com"))
+	assert.Empty(t, policy.Check("Popcorn42!", "moviefan", "fan@example.com"))
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("password1\nletmein123\n\n"), 0o600))
+
+	policy := &PasswordPolicy{MinLength: 8}
+	assert.Nil(t, policy.LoadBreachedPasswords(path))
+
+	// The list is matched regardless of case
+	assert.NotEmpty(t, policy.Check("Password1", "", ""))
+	assert.NotEmpty(t, policy.Check("letmein123", "", ""))
+	assert.Empty(t, policy.Check("popcorn-night", "", ""))
+}
+
+func TestProfileFields(t *testing.T) {
+	assert.Empty(t, Name("Ada"))
+	assert.NotEmpty(t, Name(strings.Repeat("a", MaxNameLength+1)))
+
+	assert.Empty(t, Genres([]string{"Drama", "Comedy"}))
+	assert.NotEmpty(t, Genres([]string{"Drama", " "}))
+	assert.NotEmpty(t, Genres(make([]string, MaxGenres+1)))
+}